@@ -1,15 +1,22 @@
 package main
 
 import (
+	"classification-project/internal/dataset"
 	"classification-project/internal/interface/reader"
+	"classification-project/internal/interface/writer"
 	"classification-project/internal/models"
 	"classification-project/pkg/math/statistics"
+	"classification-project/pkg/selection"
 	"classification-project/pkg/solver"
 	"flag"
 	"fmt"
 	"log/slog"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"gonum.org/v1/gonum/mat"
@@ -18,59 +25,60 @@ import (
 func main() {
 
 	params := models.InputParameters{}
-	ParseFlags(&params)
-
-	rand.NewSource(time.Now().UnixNano())
-	DustData := reader.ReadTableOrPanic("d.txt")
-	UrbanData := reader.ReadTableOrPanic("u.txt")
-	SmokeData := reader.ReadTableOrPanic("s.txt")
-	VolumeData := reader.ReadTableOrPanic("Vol.txt")
-	BetaData := reader.ReadTableOrPanic("beta.txt")
-
-	rows, cols := DustData.Rows, DustData.Columns
-	matDust := mat.NewDense(rows, cols, DustData.Data)
-	matUrban := mat.NewDense(rows, cols, UrbanData.Data)
-	matSmoke := mat.NewDense(rows, cols, SmokeData.Data)
-	matVolume := mat.NewDense(rows, cols, VolumeData.Data)
-	matBeta := mat.NewDense(rows, cols, BetaData.Data)
-
-	// Поиск максимальной области с минимальной корреляцией
-	minSize := params.MinSize
-	r1, c1, r2, c2, _, err := statistics.FindMaxAreaMinCorrelation(matDust, matUrban, minSize)
-	fmt.Println(r1, r2, c1, c2)
+	files := InputFiles{}
+	out := Output{}
+	sel := selection.Config{Rules: selection.DefaultRules()}
+	ParseFlags(&params, &files, &out, &sel)
+	if out.Dir != "" {
+		if err := os.MkdirAll(out.Dir, 0o755); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+
+	if params.Seed == 0 {
+		params.Seed = time.Now().UnixNano()
+	}
+	fmt.Printf("Seed: %d\n", params.Seed)
+	manifest, err := files.BuildManifest()
 	if err != nil {
-		fmt.Printf("Ошибка: %v\n", err)
+		fmt.Println("Error:", err)
 		return
 	}
-	matDust = matDust.Slice(r1, r2+1, c1, c2+1).(*mat.Dense)
-	matUrban = matUrban.Slice(r1, r2+1, c1, c2+1).(*mat.Dense)
-	matSmoke = matSmoke.Slice(r1, r2+1, c1, c2+1).(*mat.Dense)
-	matVolume = matVolume.Slice(r1, r2+1, c1, c2+1).(*mat.Dense)
-	matBeta = matBeta.Slice(r1, r2+1, c1, c2+1).(*mat.Dense)
+	manifest.PrintSources()
+	ds, err := dataset.Load(manifest)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	ds.Alignment.Print()
+	params.Classes, params.N, params.Beta, params.Volume = ds.Classes, ds.N, ds.Beta, ds.Volume
+	params.SigmaN, params.SigmaBeta, params.SigmaVolume = ds.SigmaN, ds.SigmaBeta, ds.SigmaVolume
 
-	DustData = models.NewTable(r2-r1+1, c2-c1+1, nil, DustData.ColumnLabels[c1:c2+1], DustData.RowLabels[r1:r2+1])
-	UrbanData = models.NewTable(r2-r1+1, c2-c1+1, nil, UrbanData.ColumnLabels[c1:c2+1], UrbanData.RowLabels[r1:r2+1])
-	SmokeData = models.NewTable(r2-r1+1, c2-c1+1, nil, SmokeData.ColumnLabels[c1:c2+1], SmokeData.RowLabels[r1:r2+1])
-	VolumeData = models.NewTable(r2-r1+1, c2-c1+1, nil, VolumeData.ColumnLabels[c1:c2+1], VolumeData.RowLabels[r1:r2+1])
-	BetaData = models.NewTable(r2-r1+1, c2-c1+1, nil, BetaData.ColumnLabels[c1:c2+1], BetaData.RowLabels[r1:r2+1])
+	valid := solver.ValidMask(params)
+	fmt.Printf("Точек без пропусков: %d из %d\n", selection.Count(valid), len(valid))
 
-	rows, cols = matDust.Dims()
-	for i := range rows {
-		for j := range cols {
-			DustData.SetWithLabels(i, j, matDust.At(i, j), DustData.RowLabels[i], DustData.ColumnLabels[j])
-			UrbanData.SetWithLabels(i, j, matUrban.At(i, j), UrbanData.RowLabels[i], UrbanData.ColumnLabels[j])
-			SmokeData.SetWithLabels(i, j, matSmoke.At(i, j), SmokeData.RowLabels[i], SmokeData.ColumnLabels[j])
-			VolumeData.SetWithLabels(i, j, matVolume.At(i, j), VolumeData.RowLabels[i], VolumeData.ColumnLabels[j])
-			BetaData.SetWithLabels(i, j, matBeta.At(i, j), BetaData.RowLabels[i], BetaData.ColumnLabels[j])
+	if err := selectPoints(&params, sel); err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		return
+	}
+	if out.Dir != "" {
+		selected := func(t *models.Table) *models.Table {
+			t = t.Sub(0, 0, t.Rows-1, t.Columns-1)
+			for k, ok := range params.Selection {
+				if !ok {
+					t.SetMissing(k/t.Columns, k%t.Columns)
+				}
+			}
+			return t
+		}
+		for _, c := range params.Classes.Classes() {
+			out.Save("region_"+c.Name, selected(params.N[c.Column]))
 		}
+		out.Save("region_beta", selected(params.Beta))
+		out.Save("region_volume", selected(params.Volume))
 	}
 
-	params.N[models.Dust] = DustData
-	params.N[models.Urban] = UrbanData
-	params.N[models.Smoke] = SmokeData
-	params.N[models.Volume] = VolumeData
-	params.N[models.Beta] = BetaData
-
 	loglevel := slog.LevelInfo
 	if params.Debug {
 		loglevel = slog.LevelDebug
@@ -79,44 +87,403 @@ func main() {
 		Level: loglevel,
 	}))
 
-	cls := solver.NewSolver(logger)
+	cls := solver.NewSolver(logger, rand.New(rand.NewSource(params.Seed)))
 	res, err := cls.Solve(params)
 	if err != nil {
 		fmt.Println("Error:", err)
 		return
 	}
 	fmt.Printf("Cv: %.3e\n", res.Cv)
-	fmt.Printf("Discrepancy: %.2e\n", res.Discrepancy)
-
-	rows, cols = params.N[models.Urban].Rows, params.N[models.Urban].Columns
-	r := mat.NewDense(rows, cols, nil)
+	for _, c := range params.Classes.Classes() {
+		if res.CvErr != nil {
+			fmt.Printf("Cv[%s]: %.3e ± %.3e\n", c.Name, res.Cv[c.Column], res.CvErr[c.Column])
+			continue
+		}
+		fmt.Printf("Cv[%s]: %.3e\n", c.Name, res.Cv[c.Column])
+	}
+	if res.CvErr != nil {
+		fmt.Printf("Weighting: %s (погрешности Cv - перенос погрешностей входных таблиц)\n", params.Weighting)
+	}
+	fmt.Printf("Discrepancy: %.2e (%s)\n", res.Discrepancy, res.Metric)
+	fmt.Printf("Method: %s (cond: %.2e, rank: %d, residual norm: %.2e)\n", res.Method, res.Cond, res.Rank, res.ResidualNorm)
+	fmt.Printf("Lambda: %.3e (%s)\n", res.Lambda, params.LambdaMethod)
 
-	for i := range rows {
-		for j := range cols {
-			tmpR := params.N[models.Volume].Get(i, j) / params.N[models.Beta].Get(i, j)
-			r.Set(i, j, (params.N[models.Dust].Get(i, j)*res.Cv[models.Dust]+
-				params.N[models.Urban].Get(i, j)*res.Cv[models.Urban]+
-				params.N[models.Smoke].Get(i, j)*res.Cv[models.Smoke]-
-				tmpR)/tmpR)
+	if params.NBootstrap > 0 {
+		br, err := cls.Bootstrap(params)
+		if err != nil {
+			fmt.Println("Bootstrap error:", err)
+		} else {
+			statistics.PrintBootstrap(br, params.Classes.Names())
+		}
+	}
 
+	if params.MCMCConfig != "" {
+		cfg, err := solver.LoadMCMCConfig(params.MCMCConfig)
+		if err != nil {
+			fmt.Println("MCMC error:", err)
+			return
 		}
+		mres, err := cls.SamplePosterior(params, cfg)
+		if err != nil {
+			fmt.Println("MCMC error:", err)
+			return
+		}
+		fmt.Printf("=== Единицы измерения для Cv  x10¹² Mm м³/м³ ===\n")
+		hist := statistics.CalculateHistograms(mres.Samples, params.Classes, 10)
+		statistics.PrintHistogramsInOrder(hist, statistics.HistogramNames(params.Classes), 50)
+		solver.PrintMCMC(mres, params.Classes.Names())
 	}
 
+	r := solver.RelativeDiscrepancy(params, res.Cv)
+
 	fmt.Printf("Relative Discrepancy Matrix:\n")
-	for i := range rows {
-		for j := range cols {
-			fmt.Printf("%+.2e  ", r.At(i, j))
+	fmt.Printf("%10s", "")
+	for _, label := range r.ColumnLabels {
+		fmt.Printf("  %9s", label)
+	}
+	fmt.Println()
+	for i := range r.Rows {
+		fmt.Printf("%10s", r.RowLabels[i])
+		for j := range r.Columns {
+			if !r.Valid(i, j) {
+				fmt.Printf("  %9s", "NaN")
+				continue
+			}
+			fmt.Printf("  %+.2e", r.Get(i, j))
 		}
 		fmt.Println()
 	}
+
+	if out.Dir != "" {
+		out.Save("residual", r)
+		out.Save("observed", solver.ObservedRatio(params))
+		out.Save("predicted", solver.PredictedRatio(params, res.Cv))
+	}
+}
+
+// Output - каталог и формат для сохранения таблиц результатов
+type Output struct {
+	Dir    string
+	Format string
+}
+
+// Save записывает таблицу в файл <Dir>/<name>.<Format> и сообщает о результате
+func (o Output) Save(name string, t *models.Table) {
+	path := filepath.Join(o.Dir, name+"."+o.Format)
+	if err := writer.WriteTableToFile(path, t, o.Format); err != nil {
+		fmt.Println("Ошибка сохранения:", err)
+		return
+	}
+	fmt.Println("Сохранено:", path)
+}
+
+// regionProgress выводит ход поиска области в stderr с шагом 10%
+func regionProgress(stage string, done, total int) {
+	if done == total || done*10/total != (done-1)*10/total {
+		fmt.Fprintf(os.Stderr, "Поиск области (%s): %d%%\n", stage, 100*done/total)
+	}
+}
+
+// rankFullSearchPoints - число точек сцены, выше которого полный перебор
+// областей с ранговой корреляцией занимает минуты и больше
+const rankFullSearchPoints = 50 * 50
+
+// selectPoints отбирает точки сцены по правилам sel.Rules и способу sel.Mode.
+// В режиме region таблицы обрезаются до найденной области, в остальных
+// режимах остаются целыми; в обоих случаях params.Selection - маска
+// отобранных точек
+func selectPoints(params *models.InputParameters, sel selection.Config) error {
+	if err := selection.ValidateMode(sel.Mode); err != nil {
+		return err
+	}
+	rows, cols := params.N[0].Rows, params.N[0].Columns
+	mask, err := selection.Build(*params, sel.Rules)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Точек по правилам отбора: %d из %d\n", selection.Count(mask), len(mask))
+
+	// Точки вне маски заменяются на NaN и не учитываются при поиске областей
+	tables := make([]*mat.Dense, len(params.N))
+	for i, n := range params.N {
+		tables[i] = selection.MaskedDense(n, mask)
+	}
+	opts := statistics.RegionOptions{
+		MinSize:  params.MinSize,
+		Workers:  params.NWorkers,
+		Step:     params.RegionStep,
+		Top:      params.RegionTop,
+		Progress: regionProgress,
+	}
+	// Корреляция Пирсона считается по префиксным суммам (Corr = nil)
+	if params.RegionCorr != statistics.CorrelationPearson {
+		if opts.Corr, err = statistics.CorrelationFunc(params.RegionCorr); err != nil {
+			return err
+		}
+		// Ранговая корреляция пересчитывается для каждой области, и полный
+		// перебор O(R²C²) областей стоит еще O(n log n) на область
+		if params.RegionStep <= 1 && sel.Mode != selection.ModeComponents &&
+			params.RegionObjective != statistics.ObjectiveCond && rows*cols > rankFullSearchPoints {
+			fmt.Fprintf(os.Stderr, "Предупреждение: корреляция %s пересчитывается для каждой области, "+
+				"полный перебор сцены %dx%d может быть очень долгим; задайте -region-step > 1\n",
+				params.RegionCorr, rows, cols)
+		}
+	}
+
+	switch sel.Mode {
+	case selection.ModeComponents:
+		kept, sizes := selection.LargestComponents(mask, rows, cols, sel.K, params.MinSize*params.MinSize)
+		if len(sizes) == 0 {
+			return fmt.Errorf("нет связных компонент из не менее %d точек", params.MinSize*params.MinSize)
+		}
+		fmt.Printf("Связные компоненты: %d, точек: %v\n", len(sizes), sizes)
+		params.Selection = kept
+		return nil
+	case selection.ModeRects:
+		if params.Classes.Len() < 2 {
+			return fmt.Errorf("выбор областей требует хотя бы двух классов")
+		}
+		regions, union, err := selection.Rectangles(tables, mask, sel.K, params.RegionObjective, opts)
+		if err != nil {
+			return err
+		}
+		statistics.PrintRegions(regions, params.RegionObjective, params.Classes.Names())
+		fmt.Printf("Точек в %d областях: %d\n", len(regions), selection.Count(union))
+		params.Selection = union
+		return nil
+	}
+
+	r1, c1, r2, c2 := 0, 0, rows-1, cols-1
+	if params.Classes.Len() >= 2 {
+		// Поиск максимальной области с минимальной корреляцией долей
+		// классов (критерий -region-objective)
+		regions, err := statistics.FindRegions(tables, params.RegionObjective, opts)
+		if err != nil {
+			return err
+		}
+		statistics.PrintRegions(regions, params.RegionObjective, params.Classes.Names())
+		if params.RegionObjective == statistics.ObjectiveDet && regions[0].Score > 1-1e-9 {
+			fmt.Println("Предупреждение: det R ≈ 0 во всех областях (доли классов в сумме дают 1?), критерий det не различает области")
+		}
+		r1, c1, r2, c2 = regions[0].R1, regions[0].C1, regions[0].R2, regions[0].C2
+		fmt.Println(r1, r2, c1, c2)
+	}
+	for i := range params.N {
+		params.N[i] = params.N[i].Sub(r1, c1, r2, c2)
+	}
+	params.Volume = params.Volume.Sub(r1, c1, r2, c2)
+	params.Beta = params.Beta.Sub(r1, c1, r2, c2)
+	sub := func(t *models.Table) *models.Table {
+		if t == nil {
+			return nil
+		}
+		return t.Sub(r1, c1, r2, c2)
+	}
+	for i := range params.SigmaN {
+		params.SigmaN[i] = sub(params.SigmaN[i])
+	}
+	params.SigmaBeta, params.SigmaVolume = sub(params.SigmaBeta), sub(params.SigmaVolume)
+	params.Selection = make([]bool, 0, (r2-r1+1)*(c2-c1+1))
+	for i := r1; i <= r2; i++ {
+		params.Selection = append(params.Selection, mask[i*cols+c1:i*cols+c2+1]...)
+	}
+	return nil
+}
+
+// InputFiles - пути к входным данным, заданные в командной строке.
+// Флаги имеют приоритет над манифестом, но заменяют только путь: масштаб,
+// единицы, лист, переменная и погрешности источника из манифеста сохраняются
+type InputFiles struct {
+	Manifest string                // JSON-манифест с путями ко всем таблицам
+	DataDir  string                // каталог с данными
+	Beta     string                // путь к таблице β
+	Volume   string                // путь к таблице V
+	Classes  *models.ClassRegistry // классы и их файлы (nil - из манифеста или d,u,s)
+	Missing  []string              // обозначения пропусков (nil - из манифеста или стандартные)
+	Align    string                // режим выравнивания по меткам ("" - из манифеста)
+	LabelTol float64               // допуск числовых меток (< 0 - из манифеста)
+	Sigma    map[string]string     // таблицы погрешностей: класс, beta или volume -> путь
 }
 
-func ParseFlags(params *models.InputParameters) {
+// BuildManifest собирает манифест из файла (или стандартных имен) и флагов
+func (f InputFiles) BuildManifest() (*dataset.Manifest, error) {
+	m := dataset.DefaultManifest(models.DefaultClasses)
+	if f.Manifest != "" {
+		var err error
+		if m, err = dataset.LoadManifest(f.Manifest); err != nil {
+			return nil, err
+		}
+	}
+	if f.DataDir != "" {
+		m.DataDir = f.DataDir
+	}
+	if f.Classes != nil {
+		// Класс из манифеста сохраняет свой источник; файл <имя>.txt, который
+		// -classes подставляет без явного файла, не заменяет путь манифеста
+		classes := dataset.DefaultManifest(f.Classes).Classes
+		for i, c := range classes {
+			for _, mc := range m.Classes {
+				if mc.Name != c.Name {
+					continue
+				}
+				path := c.Path
+				classes[i] = mc
+				if path != c.Name+".txt" {
+					classes[i].Path = path
+				}
+			}
+		}
+		m.Classes = classes
+	}
+	if f.Missing != nil {
+		m.Missing = f.Missing
+	}
+	if f.Align != "" {
+		m.Align.Mode = f.Align
+	}
+	if f.LabelTol >= 0 {
+		m.Align.Tolerance = f.LabelTol
+	}
+	if f.Beta != "" {
+		m.Beta.Path = f.Beta
+	}
+	if f.Volume != "" {
+		m.Volume.Path = f.Volume
+	}
+	for name, path := range f.Sigma {
+		switch name {
+		case "beta":
+			setSigmaPath(&m.Beta, path)
+		case "volume":
+			setSigmaPath(&m.Volume, path)
+		default:
+			found := false
+			for i := range m.Classes {
+				if m.Classes[i].Name == name {
+					setSigmaPath(&m.Classes[i].Source, path)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("погрешности для неизвестной таблицы %q (ожидается класс, beta или volume)", name)
+			}
+		}
+	}
+	return m, m.Validate()
+}
+
+// setSigmaPath задает путь к погрешностям источника, сохраняя остальные
+// поля погрешностей из манифеста
+func setSigmaPath(s *dataset.Source, path string) {
+	if s.Sigma == nil {
+		s.Sigma = &dataset.Source{}
+	}
+	s.Sigma.Path = path
+}
+
+func ParseFlags(params *models.InputParameters, files *InputFiles, out *Output, sel *selection.Config) {
+	flag.StringVar(&files.Manifest, "manifest", "", "JSON-манифест с путями к входным таблицам (форматы таблиц: "+strings.Join(reader.Formats(), ", ")+")")
+	flag.StringVar(&files.DataDir, "data-dir", "", "Каталог с входными таблицами (по умолчанию текущий или из манифеста)")
+	flag.StringVar(&files.Beta, "beta", "", "Путь к таблице β (по умолчанию beta.txt)")
+	flag.StringVar(&files.Volume, "volume", "", "Путь к таблице объемной концентрации (по умолчанию Vol.txt)")
+	flag.Func("classes", "Классы в порядке столбцов: имя[=файл],... (по умолчанию d,u,s; файл <имя>.txt)", func(v string) error {
+		classes, err := models.ParseClasses(v)
+		if err != nil {
+			return err
+		}
+		files.Classes = classes
+		return nil
+	})
+	flag.Func("missing", "Обозначения пропущенных значений через запятую (по умолчанию NaN,NA,-9999 и пустое поле)", func(v string) error {
+		files.Missing = strings.Split(v, ",")
+		return nil
+	})
+	flag.Func("sigma", "Таблицы погрешностей: имя=файл,..., где имя - класс, beta или volume", func(v string) error {
+		files.Sigma = make(map[string]string)
+		for _, item := range strings.Split(v, ",") {
+			name, path, ok := strings.Cut(item, "=")
+			if !ok || name == "" || path == "" {
+				return fmt.Errorf("ожидается имя=файл, получено %q", item)
+			}
+			files.Sigma[strings.TrimSpace(name)] = strings.TrimSpace(path)
+		}
+		return nil
+	})
+	flag.StringVar(&params.Weighting, "weighting", solver.WeightUnit, "Взвешивание строк системы: "+strings.Join(solver.Weightings, ", ")+" (sigma и both требуют таблиц погрешностей)")
+	flag.StringVar(&files.Align, "align", "", "Выравнивание таблиц по меткам: "+strings.Join(dataset.AlignModes, ", ")+" (по умолчанию intersect)")
+	flag.Float64Var(&files.LabelTol, "label-tol", -1, "Допуск для числовых меток строк и столбцов (по умолчанию 0 - точное совпадение)")
+	flag.StringVar(&out.Dir, "out-dir", "", "Каталог для сохранения подобласти, матрицы невязок и V/β (пусто - не сохранять)")
+	flag.StringVar(&out.Format, "out-format", writer.FormatTXT, "Формат сохраняемых таблиц: "+strings.Join(writer.Formats, ", "))
 	flag.IntVar(&params.NPoints, "npoints", 4, "Число точек для матрицы")
 	flag.IntVar(&params.NIters, "niters", 400, "Число повторений Монте-Карло")
 	flag.IntVar(&params.NumPointsToAvg, "navg", 10, "Количество решений для усреднения")
 	flag.Float64Var(&params.Lambda, "lambda", 0.01, "Параметр регуляризации")
+	flag.StringVar(&params.Metric, "metric", solver.MetricRelL2, "Метрика невязки для ранжирования решений: "+strings.Join(solver.Metrics, ", "))
+	flag.StringVar(&params.LambdaMethod, "lambda-method", solver.LambdaFixed, "Способ выбора λ: "+strings.Join(solver.LambdaMethods, ", "))
+	flag.StringVar(&params.LambdaScope, "lambda-scope", solver.LambdaScopeDraw, "Выбор λ для каждой выборки (draw) или по всей области (pooled)")
+	flag.Float64Var(&params.NoiseLevel, "noise", 0.05, "Относительный уровень шума V/β для принципа невязки")
+	flag.IntVar(&params.NBootstrap, "bootstrap", 0, "Число бутстреп-выборок для доверительных интервалов (0 - без бутстрепа)")
+	flag.StringVar(&params.BootstrapMethod, "ci", statistics.BootstrapBCa, "Способ построения интервалов: percentile, bca")
+	flag.StringVar(&params.MCMCConfig, "mcmc", "", "JSON-конфигурация для выборки из апостериорного распределения (MCMC)")
 	flag.BoolVar(&params.Debug, "debug", false, "Флаг отладки")
 	flag.IntVar(&params.MinSize, "min-size", 5, "Минимальный размер области")
+	flag.StringVar(&params.RegionObjective, "region-objective", statistics.ObjectivePair, "Критерий выбора области: "+strings.Join(statistics.Objectives, ", "))
+	flag.StringVar(&params.RegionCorr, "region-corr", statistics.CorrelationPearson, "Коэффициент корреляции для поиска области: "+strings.Join(statistics.Correlations, ", ")+
+		" (для spearman и kendall на больших сценах задайте -region-step > 1)")
+	flag.IntVar(&params.RegionTop, "region-top", 5, "Число выводимых областей-кандидатов")
+	flag.IntVar(&params.RegionStep, "region-step", 0, "Шаг грубой сетки поиска области с последующим уточнением границ (0 - полный перебор)")
+	flag.StringVar(&sel.Mode, "select", selection.ModeRegion, "Способ выбора точек: "+strings.Join(selection.Modes, ", "))
+	flag.IntVar(&sel.K, "select-k", 0, "Число связных компонент (0 - все) или непересекающихся областей (0 - одна) для -select components и rects")
+	flag.Func("min-fraction", "Нижние пороги долей для отбора точек: класс=порог,...", func(v string) error {
+		return parseThresholds(v, &sel.Rules.MinFraction)
+	})
+	flag.Func("max-fraction", "Верхние пороги долей для отбора точек: класс=порог,...", func(v string) error {
+		return parseThresholds(v, &sel.Rules.MaxFraction)
+	})
+	flag.Func("min-beta", "Минимальное β для отбора точек (по умолчанию без ограничения)", func(v string) error {
+		var err error
+		sel.Rules.MinBeta, err = strconv.ParseFloat(v, 64)
+		return err
+	})
+	flag.Func("altitude", "Диапазон высот (меток строк) для отбора точек: мин:макс, любая граница может быть пустой", func(v string) error {
+		lo, hi, ok := strings.Cut(v, ":")
+		if !ok {
+			return fmt.Errorf("ожидается мин:макс, получено %q", v)
+		}
+		var err error
+		if lo = strings.TrimSpace(lo); lo != "" {
+			if sel.Rules.MinAltitude, err = strconv.ParseFloat(lo, 64); err != nil {
+				return err
+			}
+		}
+		if hi = strings.TrimSpace(hi); hi != "" {
+			if sel.Rules.MaxAltitude, err = strconv.ParseFloat(hi, 64); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	flag.Float64Var(&sel.Rules.MinDecorrelation, "min-decorrelation", 0, "Минимальная локальная декорреляция 1 - max|r| долей для отбора точек (0 - без ограничения)")
+	flag.IntVar(&sel.Rules.Radius, "decorrelation-radius", sel.Rules.Radius, "Радиус окна для локальной корреляции долей")
+	flag.StringVar(&params.Method, "method", solver.MethodCholesky, "Метод решения системы: "+strings.Join(solver.Methods, ", "))
+	flag.Int64Var(&params.Seed, "seed", 0, "Зерно генератора случайных чисел (0 - по текущему времени)")
+	flag.IntVar(&params.NWorkers, "nworkers", runtime.NumCPU(), "Число потоков для Монте-Карло")
 	flag.Parse()
 }
+
+// parseThresholds разбирает пороги долей вида класс=порог,...
+func parseThresholds(v string, limits *map[string]float64) error {
+	*limits = make(map[string]float64)
+	for _, item := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("ожидается класс=порог, получено %q", item)
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return err
+		}
+		(*limits)[strings.TrimSpace(name)] = limit
+	}
+	return nil
+}
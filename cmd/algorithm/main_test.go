@@ -0,0 +1,199 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"classification-project/internal/dataset"
+	"classification-project/internal/models"
+	"classification-project/pkg/math/statistics"
+	"classification-project/pkg/selection"
+)
+
+// TestBuildManifestFlags проверяет, что флаги командной строки имеют
+// приоритет над значениями манифеста
+func TestBuildManifestFlags(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "scene.json")
+	content := `{
+		"data_dir": "data",
+		"beta": {"path": "beta.nc", "variable": "beta"},
+		"volume": {"path": "Vol.txt", "scale": 2},
+		"classes": [{"name": "u", "path": "u.txt"}, {"name": "d", "path": "d.txt"}],
+		"missing": ["-999"],
+		"align": {"mode": "intersect", "tolerance": 0.5}
+	}`
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Без флагов остаются значения манифеста
+	m, err := InputFiles{Manifest: filename, LabelTol: -1}.BuildManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.DataDir != filepath.Join(dir, "data") || m.Beta.Path != "beta.nc" || m.Volume.Scale != 2 ||
+		len(m.Classes) != 2 || m.Align.Mode != "intersect" || m.Align.Tolerance != 0.5 {
+		t.Errorf("значения манифеста изменены: %+v", m)
+	}
+
+	classes, err := models.ParseClasses("x,y=y.dat,z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = InputFiles{
+		Manifest: filename,
+		DataDir:  "/scene",
+		Beta:     "b.txt",
+		Volume:   "v.txt",
+		Classes:  classes,
+		Missing:  []string{"NA"},
+		Align:    "strict",
+		LabelTol: 0,
+		Sigma:    map[string]string{"y": "sy.txt", "volume": "sv.txt"},
+	}.BuildManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.DataDir != "/scene" {
+		t.Errorf("DataDir = %q", m.DataDir)
+	}
+	// Флаг пути заменяет только путь, переменная и масштаб сохраняются
+	if m.Beta != (dataset.Source{Path: "b.txt", Variable: "beta"}) {
+		t.Errorf("beta = %+v", m.Beta)
+	}
+	if m.Volume.Path != "v.txt" || m.Volume.Scale != 2 || m.Volume.Sigma == nil || m.Volume.Sigma.Path != "sv.txt" {
+		t.Errorf("volume = %+v", m.Volume)
+	}
+	if len(m.Classes) != 3 || m.Classes[0].Name != "x" || m.Classes[1].Path != "y.dat" || m.Classes[1].Sigma == nil || m.Classes[1].Sigma.Path != "sy.txt" {
+		t.Errorf("классы = %+v", m.Classes)
+	}
+	if len(m.Missing) != 1 || m.Missing[0] != "NA" {
+		t.Errorf("missing = %v", m.Missing)
+	}
+	if m.Align.Mode != "strict" || m.Align.Tolerance != 0 {
+		t.Errorf("align = %+v", m.Align)
+	}
+
+	if _, err := (InputFiles{Manifest: filename, LabelTol: -1, Sigma: map[string]string{"q": "s.txt"}}).BuildManifest(); err == nil {
+		t.Error("ожидалась ошибка для погрешностей неизвестной таблицы")
+	}
+}
+
+func TestBuildManifestFlagsKeepMetadata(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "scene.json")
+	content := `{
+		"beta": {"path": "beta.xlsx", "sheet": "beta", "unit": "1/Mm/sr", "scale": 2,
+			"sigma": {"path": "sbeta.xlsx", "sheet": "sigma"}},
+		"volume": {"path": "vol.txt"},
+		"classes": [
+			{"name": "u", "path": "classes.xlsx", "sheet": "u", "sigma": {"path": "su.txt"}},
+			{"name": "d", "path": "d.txt", "scale": 0.5}
+		]
+	}`
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	classes, err := models.ParseClasses("d=d2.txt,u,w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := InputFiles{
+		Manifest: filename,
+		Beta:     "beta2.xlsx",
+		Classes:  classes,
+		LabelTol: -1,
+		Sigma:    map[string]string{"beta": "sbeta2.xlsx"},
+	}.BuildManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Beta.Path != "beta2.xlsx" || m.Beta.Sheet != "beta" || m.Beta.Unit != "1/Mm/sr" || m.Beta.Scale != 2 {
+		t.Errorf("beta = %+v", m.Beta)
+	}
+	if m.Beta.Sigma == nil || m.Beta.Sigma.Path != "sbeta2.xlsx" || m.Beta.Sigma.Sheet != "sigma" {
+		t.Errorf("погрешности beta = %+v", m.Beta.Sigma)
+	}
+	if len(m.Classes) != 3 {
+		t.Fatalf("классы = %+v", m.Classes)
+	}
+	// Порядок задает флаг, метаданные классов берутся из манифеста
+	if d := m.Classes[0]; d.Name != "d" || d.Path != "d2.txt" || d.Scale != 0.5 {
+		t.Errorf("класс d = %+v", d)
+	}
+	if u := m.Classes[1]; u.Name != "u" || u.Path != "classes.xlsx" || u.Sheet != "u" || u.Sigma == nil || u.Sigma.Path != "su.txt" {
+		t.Errorf("класс u = %+v", u)
+	}
+	if w := m.Classes[2]; w.Name != "w" || w.Path != "w.txt" || w.Sigma != nil {
+		t.Errorf("класс w = %+v", w)
+	}
+}
+
+// sceneParams строит сцену rows x cols со случайными долями классов
+func sceneParams(rows, cols int) models.InputParameters {
+	rng := rand.New(rand.NewSource(1))
+	rowLabels := make([]string, rows)
+	for i := range rowLabels {
+		rowLabels[i] = strconv.Itoa(1000 + 10*i)
+	}
+	colLabels := make([]string, cols)
+	for j := range colLabels {
+		colLabels[j] = "C" + strconv.Itoa(j)
+	}
+	table := func(value func() float64) *models.Table {
+		data := make([]float64, rows*cols)
+		for k := range data {
+			data[k] = value()
+		}
+		return models.NewTable(rows, cols, data, colLabels, rowLabels)
+	}
+	p := models.InputParameters{
+		Classes:         models.DefaultClasses,
+		Beta:            table(func() float64 { return 0.5 + rng.Float64() }),
+		Volume:          table(func() float64 { return 1 + rng.Float64() }),
+		MinSize:         2,
+		RegionObjective: statistics.ObjectivePair,
+		RegionTop:       1,
+		NWorkers:        1,
+	}
+	for range p.Classes.Len() {
+		p.N = append(p.N, table(rng.Float64))
+	}
+	return p
+}
+
+// TestSelectPointsRankCorrelation проверяет, что ранговые корреляции
+// работают с шагом по умолчанию (полный перебор) и с грубой сеткой
+func TestSelectPointsRankCorrelation(t *testing.T) {
+	for _, tt := range []struct {
+		corr, objective, mode string
+		step                  int
+	}{
+		{statistics.CorrelationSpearman, statistics.ObjectivePair, selection.ModeRegion, 0},
+		{statistics.CorrelationKendall, statistics.ObjectiveMax, selection.ModeRegion, 0},
+		{statistics.CorrelationKendall, statistics.ObjectiveMax, selection.ModeRects, 0},
+		{statistics.CorrelationSpearman, statistics.ObjectiveDet, selection.ModeRegion, 2},
+	} {
+		p := sceneParams(8, 8)
+		p.RegionCorr, p.RegionObjective, p.RegionStep = tt.corr, tt.objective, tt.step
+		err := selectPoints(&p, selection.Config{Mode: tt.mode, K: 1, Rules: selection.DefaultRules()})
+		if err != nil {
+			t.Errorf("%s, %s, %s, step %d: %v", tt.corr, tt.objective, tt.mode, tt.step, err)
+			continue
+		}
+		if n := selection.Count(p.Selection); n < p.MinSize*p.MinSize {
+			t.Errorf("%s, %s, %s: отобрано %d точек", tt.corr, tt.objective, tt.mode, n)
+		}
+	}
+
+	p := sceneParams(8, 8)
+	p.RegionCorr = "pearsonn"
+	if err := selectPoints(&p, selection.Config{Mode: selection.ModeRegion, Rules: selection.DefaultRules()}); err == nil {
+		t.Error("ожидалась ошибка для неизвестной корреляции")
+	}
+}
@@ -40,8 +40,8 @@ func main() {
 
 	// Простой расчет гистограмм
 	fmt.Println("=== Простой расчет гистограмм ===")
-	simpleResults := statistics.CalculateHistograms(solutions, 10)
-	statistics.PrintAllHistograms(simpleResults, 50)
+	simpleResults := statistics.CalculateHistograms(solutions, models.DefaultClasses, 10)
+	statistics.PrintHistogramsInOrder(simpleResults, statistics.HistogramNames(models.DefaultClasses), 50)
 
 	// Вывод статистики для Discrepancy
 	fmt.Println("\n=== Статистика для Discrepancy ===")
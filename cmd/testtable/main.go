@@ -1,31 +1,64 @@
 package main
 
 import (
+	"classification-project/internal/dataset"
 	"classification-project/internal/interface/reader"
 	"classification-project/internal/models"
 	"classification-project/pkg/math/statistics"
+	"flag"
 	"fmt"
 	"math"
+	"strings"
 
 	"gonum.org/v1/gonum/mat"
 )
 
 func main() {
 
-	var N [models.Total]*models.Table
-	for i := range models.TotalCv {
-		N[i] = reader.ReadTableOrPanic(fmt.Sprintf("%s.txt", models.ClassificationName[i]))
+	manifestPath := flag.String("manifest", "", "JSON-манифест с путями к входным таблицам (форматы таблиц: "+strings.Join(reader.Formats(), ", ")+")")
+	dataDir := flag.String("data-dir", "", "Каталог с таблицами долей классов")
+	align := flag.String("align", "", "Выравнивание таблиц по меткам: "+strings.Join(dataset.AlignModes, ", ")+" (по умолчанию intersect)")
+	labelTol := flag.Float64("label-tol", -1, "Допуск для числовых меток строк и столбцов (по умолчанию 0 - точное совпадение)")
+	flag.Parse()
+
+	manifest := dataset.DefaultManifest(models.DefaultClasses)
+	if *manifestPath != "" {
+		var err error
+		if manifest, err = dataset.LoadManifest(*manifestPath); err != nil {
+			fmt.Printf("Ошибка: %v\n", err)
+			return
+		}
+	}
+	if *dataDir != "" {
+		manifest.DataDir = *dataDir
+	}
+	if *align != "" {
+		manifest.Align.Mode = *align
+	}
+	if *labelTol >= 0 {
+		manifest.Align.Tolerance = *labelTol
+	}
+	ds, err := dataset.LoadClasses(manifest)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		return
+	}
+	ds.Alignment.Print()
+	if ds.Classes.Len() < 2 {
+		fmt.Println("Ошибка: нужно хотя бы два класса")
+		return
 	}
+	classes, N := ds.Classes.Classes(), ds.N
 
-	rows, cols := N[models.Dust].Rows, N[models.Dust].Columns
-	A := mat.NewDense(rows, cols, N[models.Dust].Data)
-	B := mat.NewDense(rows, cols, N[models.Urban].Data)
+	rows, cols := N[0].Rows, N[0].Columns
+	A := mat.NewDense(rows, cols, N[0].Data)
+	B := mat.NewDense(rows, cols, N[1].Data)
 
-	fmt.Print("Матрица D: ")
+	fmt.Printf("Матрица %s: ", classes[0].Name)
 	fa := mat.Formatted(A, mat.Prefix("           "), mat.Squeeze())
 	fmt.Printf("%.2f\n", fa)
 
-	fmt.Print("\nМатрица U: ")
+	fmt.Printf("\nМатрица %s: ", classes[1].Name)
 	fb := mat.Formatted(B, mat.Prefix("           "), mat.Squeeze())
 	fmt.Printf("%.2f\n", fb)
 
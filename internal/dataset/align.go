@@ -0,0 +1,299 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"classification-project/internal/models"
+)
+
+// Режимы выравнивания таблиц по меткам
+const (
+	AlignStrict    = "strict"    // метки всех таблиц должны совпадать, иначе ошибка
+	AlignIntersect = "intersect" // остаются только метки, общие для всех таблиц
+	AlignJoin      = "join"      // объединение меток, недостающие ячейки - пропуски
+)
+
+// AlignModes - допустимые режимы выравнивания
+var AlignModes = []string{AlignStrict, AlignIntersect, AlignJoin}
+
+// AlignOptions - параметры сопоставления строк и столбцов таблиц по меткам
+type AlignOptions struct {
+	Mode string `json:"mode,omitempty"` // режим выравнивания ("" - intersect)
+	// Допуск для числовых меток (например высот "1012.5"): метки совпадают,
+	// если |a-b| <= Tolerance. 0 - только точное совпадение строк
+	Tolerance float64 `json:"tolerance,omitempty"`
+}
+
+// AxisReport - итог выравнивания одной оси (строк или столбцов) одной таблицы
+type AxisReport struct {
+	Dropped []string // метки таблицы, отброшенные при пересечении
+	Filled  []string // метки, отсутствующие в таблице и заполненные пропусками
+	Renamed int      // число меток, сопоставленных по допуску, а не точно
+}
+
+// Empty проверяет, что ось таблицы не изменилась
+func (r AxisReport) Empty() bool {
+	return len(r.Dropped) == 0 && len(r.Filled) == 0 && r.Renamed == 0
+}
+
+// TableReport - итог выравнивания одной таблицы
+type TableReport struct {
+	Name    string
+	Rows    AxisReport
+	Columns AxisReport
+}
+
+// AlignReport - отчет о выравнивании всех таблиц
+type AlignReport struct {
+	Options AlignOptions
+	Rows    int // число строк после выравнивания
+	Columns int // число столбцов после выравнивания
+	Tables  []TableReport
+}
+
+// Changed проверяет, была ли изменена хотя бы одна таблица
+func (r AlignReport) Changed() bool {
+	for _, t := range r.Tables {
+		if !t.Rows.Empty() || !t.Columns.Empty() {
+			return true
+		}
+	}
+	return false
+}
+
+// Print выводит отчет о выравнивании
+func (r AlignReport) Print() {
+	fmt.Printf("=== Выравнивание таблиц по меткам (%s, допуск %g) ===\n", r.Options.Mode, r.Options.Tolerance)
+	fmt.Printf("Размер после выравнивания: %d x %d\n", r.Rows, r.Columns)
+	if !r.Changed() {
+		fmt.Println("Метки всех таблиц совпадают")
+		return
+	}
+	for _, line := range r.lines() {
+		fmt.Println(line)
+	}
+}
+
+// lines возвращает по строке на каждое изменение осей таблиц
+func (r AlignReport) lines() []string {
+	var lines []string
+	axis := func(name, kind string, a AxisReport) {
+		if len(a.Dropped) > 0 {
+			lines = append(lines, fmt.Sprintf("%-8s отброшены %s (%d): %s", name, kind, len(a.Dropped), strings.Join(a.Dropped, ", ")))
+		}
+		if len(a.Filled) > 0 {
+			lines = append(lines, fmt.Sprintf("%-8s нет %s, заполнены пропусками (%d): %s", name, kind, len(a.Filled), strings.Join(a.Filled, ", ")))
+		}
+		if a.Renamed > 0 {
+			lines = append(lines, fmt.Sprintf("%-8s %s, сопоставленные по допуску: %d", name, kind, a.Renamed))
+		}
+	}
+	for _, t := range r.Tables {
+		axis(t.Name, "строки", t.Rows)
+		axis(t.Name, "столбцы", t.Columns)
+	}
+	return lines
+}
+
+// Align сопоставляет строки и столбцы таблиц по меткам. Порядок и метки
+// результата берутся из первой таблицы, при объединении новые метки
+// добавляются в конец (числовые метки сортируются по значению).
+// names - имена таблиц для отчета
+func Align(tables []*models.Table, names []string, opts AlignOptions) ([]*models.Table, AlignReport, error) {
+	if opts.Mode == "" {
+		opts.Mode = AlignIntersect
+	}
+	report := AlignReport{Options: opts, Tables: make([]TableReport, len(tables))}
+	switch opts.Mode {
+	case AlignStrict, AlignIntersect, AlignJoin:
+	default:
+		return nil, report, fmt.Errorf("неизвестный режим выравнивания %q, допустимые: %s", opts.Mode, strings.Join(AlignModes, ", "))
+	}
+	if opts.Tolerance < 0 {
+		return nil, report, fmt.Errorf("отрицательный допуск меток: %g", opts.Tolerance)
+	}
+
+	rowLabels := make([][]string, len(tables))
+	colLabels := make([][]string, len(tables))
+	for k, t := range tables {
+		rowLabels[k], colLabels[k] = t.RowLabels, t.ColumnLabels
+		report.Tables[k].Name = names[k]
+	}
+	rows, rowIdx, err := alignAxis(rowLabels, opts)
+	if err != nil {
+		return nil, report, fmt.Errorf("строки: %v", err)
+	}
+	cols, colIdx, err := alignAxis(colLabels, opts)
+	if err != nil {
+		return nil, report, fmt.Errorf("столбцы: %v", err)
+	}
+	if len(rows) == 0 || len(cols) == 0 {
+		return nil, report, fmt.Errorf("нет общих меток: %d строк, %d столбцов", len(rows), len(cols))
+	}
+	report.Rows, report.Columns = len(rows), len(cols)
+	for k := range tables {
+		report.Tables[k].Rows = axisReport(rowLabels[k], rows, rowIdx[k])
+		report.Tables[k].Columns = axisReport(colLabels[k], cols, colIdx[k])
+	}
+	if opts.Mode == AlignStrict && report.Changed() {
+		return nil, report, fmt.Errorf("метки таблиц не совпадают (режим %s):\n%s", AlignStrict, strings.Join(report.lines(), "\n"))
+	}
+
+	aligned := make([]*models.Table, len(tables))
+	for k, t := range tables {
+		out := models.NewTable(len(rows), len(cols), nil,
+			append([]string(nil), cols...), append([]string(nil), rows...))
+		for i, ri := range rowIdx[k] {
+			for j, cj := range colIdx[k] {
+				if ri < 0 || cj < 0 || !t.Valid(ri, cj) {
+					out.SetMissing(i, j)
+					continue
+				}
+				out.Set(i, j, t.Get(ri, cj))
+			}
+		}
+		aligned[k] = out
+	}
+	return aligned, report, nil
+}
+
+// alignAxis строит общую ось для наборов меток и для каждого набора
+// индексы его меток на общей оси (-1 - метки нет в наборе)
+func alignAxis(labels [][]string, opts AlignOptions) ([]string, [][]int, error) {
+	for k, ls := range labels {
+		seen := make(map[string]bool, len(ls))
+		for _, l := range ls {
+			if seen[l] {
+				return nil, nil, fmt.Errorf("таблица %d: повторяющаяся метка %q", k+1, l)
+			}
+			seen[l] = true
+		}
+	}
+
+	axis := append([]string(nil), labels[0]...)
+	if opts.Mode == AlignJoin {
+		for _, ls := range labels[1:] {
+			for _, l := range ls {
+				if matchLabel(axis, l, opts.Tolerance, nil) < 0 {
+					axis = append(axis, l)
+				}
+			}
+		}
+		sortNumericLabels(axis)
+	}
+
+	index := make([][]int, len(labels))
+	for k, ls := range labels {
+		index[k] = make([]int, len(axis))
+		used := make([]bool, len(ls))
+		for i, l := range axis {
+			index[k][i] = matchLabel(ls, l, opts.Tolerance, used)
+			if index[k][i] >= 0 {
+				used[index[k][i]] = true
+			}
+		}
+	}
+
+	if opts.Mode != AlignIntersect {
+		return axis, index, nil
+	}
+	// Пересечение: оставляем метки, найденные во всех таблицах
+	var common []string
+	commonIdx := make([][]int, len(labels))
+	for i, l := range axis {
+		found := true
+		for k := range labels {
+			found = found && index[k][i] >= 0
+		}
+		if !found {
+			continue
+		}
+		common = append(common, l)
+		for k := range labels {
+			commonIdx[k] = append(commonIdx[k], index[k][i])
+		}
+	}
+	return common, commonIdx, nil
+}
+
+// matchLabel ищет метку label среди labels: сначала точное совпадение, затем
+// ближайшую числовую метку в пределах допуска. Занятые (used) метки пропускаются
+func matchLabel(labels []string, label string, tol float64, used []bool) int {
+	for i, l := range labels {
+		if l == label && (used == nil || !used[i]) {
+			return i
+		}
+	}
+	if tol == 0 {
+		return -1
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(label), 64)
+	if err != nil {
+		return -1
+	}
+	best, bestDiff := -1, math.Inf(1)
+	for i, l := range labels {
+		if used != nil && used[i] {
+			continue
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(l), 64)
+		if err != nil {
+			continue
+		}
+		if d := math.Abs(v - w); d <= tol && d < bestDiff {
+			best, bestDiff = i, d
+		}
+	}
+	return best
+}
+
+// sortNumericLabels сортирует метки по значению, если все они числовые
+func sortNumericLabels(labels []string) {
+	values := make([]float64, len(labels))
+	for i, l := range labels {
+		v, err := strconv.ParseFloat(strings.TrimSpace(l), 64)
+		if err != nil {
+			return
+		}
+		values[i] = v
+	}
+	sort.Sort(byValue{labels, values})
+}
+
+type byValue struct {
+	labels []string
+	values []float64
+}
+
+func (b byValue) Len() int           { return len(b.labels) }
+func (b byValue) Less(i, j int) bool { return b.values[i] < b.values[j] }
+func (b byValue) Swap(i, j int) {
+	b.labels[i], b.labels[j] = b.labels[j], b.labels[i]
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+}
+
+// axisReport сравнивает метки таблицы с общей осью
+func axisReport(own, axis []string, index []int) AxisReport {
+	var r AxisReport
+	used := make([]bool, len(own))
+	for i, idx := range index {
+		if idx < 0 {
+			r.Filled = append(r.Filled, axis[i])
+			continue
+		}
+		used[idx] = true
+		if own[idx] != axis[i] {
+			r.Renamed++
+		}
+	}
+	for i, l := range own {
+		if !used[i] {
+			r.Dropped = append(r.Dropped, l)
+		}
+	}
+	return r
+}
@@ -0,0 +1,73 @@
+package dataset
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"classification-project/internal/models"
+)
+
+func TestAlign(t *testing.T) {
+	// Таблица b содержит лишнюю высоту 1020 и записывает высоты с погрешностью
+	a := models.NewTable(2, 2, []float64{1, 2, 3, 4}, []string{"A", "B"}, []string{"1005", "1012.5"})
+	b := models.NewTable(3, 2, []float64{50, 60, 10, 20, 30, 40}, []string{"B", "A"}, []string{"1020", "1005.0001", "1012.4999"})
+
+	tests := []struct {
+		name    string
+		opts    AlignOptions
+		rows    []string
+		b       []float64
+		wantErr bool
+	}{
+		{name: "точное совпадение", opts: AlignOptions{Mode: AlignIntersect}, wantErr: true},
+		{
+			name: "пересечение с допуском",
+			opts: AlignOptions{Mode: AlignIntersect, Tolerance: 0.01},
+			rows: []string{"1005", "1012.5"},
+			b:    []float64{20, 10, 40, 30},
+		},
+		{
+			name: "объединение с допуском",
+			opts: AlignOptions{Mode: AlignJoin, Tolerance: 0.01},
+			rows: []string{"1005", "1012.5", "1020"},
+			b:    []float64{20, 10, 40, 30, 60, 50},
+		},
+		{name: "строгий режим", opts: AlignOptions{Mode: AlignStrict, Tolerance: 0.01}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aligned, report, err := Align([]*models.Table{a, b}, []string{"a", "b"}, tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ожидалась ошибка")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			for _, table := range aligned {
+				if !reflect.DeepEqual(table.RowLabels, tt.rows) || !reflect.DeepEqual(table.ColumnLabels, []string{"A", "B"}) {
+					t.Fatalf("метки: %q x %q", table.RowLabels, table.ColumnLabels)
+				}
+			}
+			if !reflect.DeepEqual(aligned[1].Data, tt.b) {
+				t.Errorf("данные b: %v, ожидалось %v", aligned[1].Data, tt.b)
+			}
+			if got := report.Tables[1].Rows.Renamed; got != 2 {
+				t.Errorf("сопоставлено по допуску: %d, ожидалось 2", got)
+			}
+			if tt.opts.Mode == AlignJoin {
+				if !reflect.DeepEqual(report.Tables[0].Rows.Filled, []string{"1020"}) {
+					t.Errorf("заполнено в a: %q", report.Tables[0].Rows.Filled)
+				}
+				if aligned[0].Valid(2, 0) || !math.IsNaN(aligned[0].Get(2, 1)) {
+					t.Error("недостающая строка a должна быть пропуском")
+				}
+			} else if !reflect.DeepEqual(report.Tables[1].Rows.Dropped, []string{"1020"}) {
+				t.Errorf("отброшено в b: %q", report.Tables[1].Rows.Dropped)
+			}
+		})
+	}
+}
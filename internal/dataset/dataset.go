@@ -0,0 +1,202 @@
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"classification-project/internal/interface/reader"
+	"classification-project/internal/models"
+)
+
+// Dataset - все входные таблицы одной сцены, выровненные по меткам строк и столбцов
+type Dataset struct {
+	Classes *models.ClassRegistry
+	N       []*models.Table // доли классов в порядке столбцов реестра
+	Beta    *models.Table
+	Volume  *models.Table
+	// Таблицы погрешностей (nil - не заданы в манифесте); SigmaN - nil,
+	// если погрешности не заданы ни для одного класса
+	SigmaN      []*models.Table
+	SigmaBeta   *models.Table
+	SigmaVolume *models.Table
+	Alignment   AlignReport // что было отброшено или дополнено при выравнивании
+}
+
+// Load читает все таблицы манифеста, применяет масштабы и выравнивает
+// таблицы по меткам (см. Manifest.Align)
+func Load(m *Manifest) (*Dataset, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	ds, err := m.loadClasses()
+	if err != nil {
+		return nil, err
+	}
+	if ds.Beta, err = m.load(m.Beta); err != nil {
+		return nil, err
+	}
+	if ds.Volume, err = m.load(m.Volume); err != nil {
+		return nil, err
+	}
+	if ds.SigmaBeta, err = m.loadSigma(m.Beta); err != nil {
+		return nil, err
+	}
+	if ds.SigmaVolume, err = m.loadSigma(m.Volume); err != nil {
+		return nil, err
+	}
+	return ds, ds.align(m.Align)
+}
+
+// LoadClasses читает и выравнивает только таблицы долей классов и их погрешностей;
+// Beta и Volume остаются nil
+func LoadClasses(m *Manifest) (*Dataset, error) {
+	ds, err := m.loadClasses()
+	if err != nil {
+		return nil, err
+	}
+	return ds, ds.align(m.Align)
+}
+
+func (m *Manifest) loadClasses() (*Dataset, error) {
+	classes, err := m.Registry()
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &Dataset{Classes: classes, N: make([]*models.Table, classes.Len())}
+	for i, c := range m.Classes {
+		if ds.N[i], err = m.load(c.Source); err != nil {
+			return nil, err
+		}
+		if c.Sigma == nil {
+			continue
+		}
+		if ds.SigmaN == nil {
+			ds.SigmaN = make([]*models.Table, classes.Len())
+		}
+		if ds.SigmaN[i], err = m.loadSigma(c.Source); err != nil {
+			return nil, err
+		}
+	}
+	return ds, nil
+}
+
+// align выравнивает все загруженные таблицы по первой таблице класса
+func (ds *Dataset) align(opts AlignOptions) error {
+	var tables []*models.Table
+	var names []string
+	var targets []**models.Table
+	add := func(name string, t **models.Table) {
+		if *t != nil {
+			tables, names, targets = append(tables, *t), append(names, name), append(targets, t)
+		}
+	}
+	classes := ds.Classes.Names()
+	for i, name := range classes {
+		add(name, &ds.N[i])
+	}
+	add("beta", &ds.Beta)
+	add("volume", &ds.Volume)
+	for i := range ds.SigmaN {
+		add("sigma_"+classes[i], &ds.SigmaN[i])
+	}
+	add("sigma_beta", &ds.SigmaBeta)
+	add("sigma_volume", &ds.SigmaVolume)
+
+	aligned, report, err := Align(tables, names, opts)
+	ds.Alignment = report
+	if err != nil {
+		return fmt.Errorf("выравнивание таблиц: %v", err)
+	}
+	for k, t := range targets {
+		*t = aligned[k]
+	}
+	return nil
+}
+
+// loadSigma читает таблицу погрешностей источника s (nil, если она не задана).
+// Без собственного масштаба погрешности масштабируются как данные
+func (m *Manifest) loadSigma(s Source) (*models.Table, error) {
+	if s.Sigma == nil {
+		return nil, nil
+	}
+	sigma := *s.Sigma
+	if sigma.Scale == 0 {
+		sigma.Scale = s.Scale
+	}
+	return m.load(sigma)
+}
+
+func (m *Manifest) load(s Source) (*models.Table, error) {
+	path := m.Resolve(s.Path)
+	table, err := reader.Open(path, reader.Options{
+		MissingValues: m.Missing,
+		Variable:      s.Variable,
+		RowDim:        s.RowDim,
+		Sheet:         s.Sheet,
+	})
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if s.Scale != 0 && s.Scale != 1 {
+		for i := range table.Data {
+			table.Data[i] *= s.Scale
+		}
+	}
+	return table, nil
+}
+
+// PrintSources выводит список входных файлов с единицами и масштабами
+func (m *Manifest) PrintSources() {
+	fmt.Println("=== Входные файлы ===")
+	line := func(name string, s Source) {
+		scale := s.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		unit := s.Unit
+		if unit == "" {
+			unit = "-"
+		}
+		path := m.Resolve(s.Path)
+		if s.Variable != "" {
+			path += ":" + s.Variable
+		}
+		if s.Sheet != "" {
+			path += "[" + s.Sheet + "]"
+		}
+		fmt.Printf("%-8s %s (unit: %s, scale: %g)\n", name, path, unit, scale)
+	}
+	sigma := func(name string, s Source) {
+		if s.Sigma != nil {
+			src := *s.Sigma
+			if src.Scale == 0 {
+				src.Scale = s.Scale
+			}
+			if src.Unit == "" {
+				src.Unit = s.Unit
+			}
+			line("sigma_"+name, src)
+		}
+	}
+	for _, c := range m.Classes {
+		line(c.Name, c.Source)
+	}
+	line("beta", m.Beta)
+	line("volume", m.Volume)
+	for _, c := range m.Classes {
+		sigma(c.Name, c.Source)
+	}
+	sigma("beta", m.Beta)
+	sigma("volume", m.Volume)
+	missing := m.Missing
+	if missing == nil {
+		missing = reader.DefaultMissingValues
+	}
+	fmt.Printf("Пропуски: %q\n", missing)
+}
@@ -0,0 +1,55 @@
+package dataset
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestLoadSigma проверяет чтение таблиц погрешностей: масштаб наследуется от
+// данных, а строки выравниваются вместе с остальными таблицами
+func TestLoadSigma(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"d.txt":       "\tA\tB\n1005\t0.5\t0.5\n1012.5\t0.4\t0.6\n",
+		"beta.txt":    "\tA\tB\n1005\t1\t2\n1012.5\t3\t4\n",
+		"Vol.txt":     "\tA\tB\n1005\t5\t6\n1012.5\t7\t8\n",
+		"sigma_d.txt": "\tA\tB\n1005\t0.01\t0.02\n1012.5\t0.03\t0.04\n",
+		// Лишняя строка 1020 отбрасывается при пересечении
+		"sigma_vol.txt": "\tA\tB\n1020\t9\t9\n1005\t0.5\t0.6\n1012.5\t0.7\t0.8\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &Manifest{
+		DataDir: dir,
+		Beta:    Source{Path: "beta.txt"},
+		Volume:  Source{Path: "Vol.txt", Scale: 10, Sigma: &Source{Path: "sigma_vol.txt"}},
+		Classes: []ClassSource{{Name: "d", Source: Source{Path: "d.txt", Sigma: &Source{Path: "sigma_d.txt", Scale: 2}}}},
+	}
+
+	ds, err := Load(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.SigmaBeta != nil || len(ds.SigmaN) != 1 {
+		t.Fatalf("погрешности: beta %v, доли %d", ds.SigmaBeta, len(ds.SigmaN))
+	}
+	if want := []float64{0.02, 0.04, 0.06, 0.08}; !reflect.DeepEqual(ds.SigmaN[0].Data, want) {
+		t.Errorf("погрешности d: %v, ожидалось %v", ds.SigmaN[0].Data, want)
+	}
+	if want := []float64{5, 6, 7, 8}; !reflect.DeepEqual(ds.SigmaVolume.Data, want) {
+		t.Errorf("погрешности V: %v, ожидалось %v", ds.SigmaVolume.Data, want)
+	}
+	if want := []string{"1005", "1012.5"}; !reflect.DeepEqual(ds.SigmaVolume.RowLabels, want) {
+		t.Errorf("метки строк погрешностей V: %q", ds.SigmaVolume.RowLabels)
+	}
+
+	m.Beta.Sigma = &Source{}
+	if _, err := Load(m); err == nil {
+		t.Error("ожидалась ошибка для погрешностей без пути")
+	}
+}
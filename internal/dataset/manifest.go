@@ -0,0 +1,134 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"classification-project/internal/models"
+)
+
+// Source описывает один входной файл
+type Source struct {
+	Path  string  `json:"path"`
+	Unit  string  `json:"unit,omitempty"`  // единицы измерения, только для отчета
+	Scale float64 `json:"scale,omitempty"` // множитель для значений (0 - без масштабирования)
+	// Для файлов NetCDF: имя двумерной переменной и измерение строк таблицы
+	Variable string `json:"variable,omitempty"`
+	RowDim   string `json:"row_dim,omitempty"`
+	// Для книг xlsx: имя листа ("" - первый лист)
+	Sheet string `json:"sheet,omitempty"`
+	// Таблица погрешностей (σ) этих данных с теми же метками; nil - не задана.
+	// Если масштаб погрешности не указан, используется масштаб данных
+	Sigma *Source `json:"sigma,omitempty"`
+}
+
+// ClassSource - файл долей одного класса
+type ClassSource struct {
+	Name string `json:"name"`
+	Source
+}
+
+// Manifest сопоставляет входным данным пути к файлам
+type Manifest struct {
+	// Каталог, от которого считаются относительные пути.
+	// Относительный DataDir считается от каталога файла манифеста
+	DataDir string        `json:"data_dir,omitempty"`
+	Beta    Source        `json:"beta"`
+	Volume  Source        `json:"volume"`
+	Classes []ClassSource `json:"classes"` // порядок задает столбцы матрицы системы
+	// Обозначения пропущенных значений во всех файлах (nil - reader.DefaultMissingValues)
+	Missing []string `json:"missing,omitempty"`
+	// Сопоставление строк и столбцов таблиц по меткам
+	Align AlignOptions `json:"align"`
+}
+
+// DefaultManifest возвращает манифест со стандартными именами файлов
+// beta.txt, Vol.txt и файлами классов из реестра
+func DefaultManifest(classes *models.ClassRegistry) *Manifest {
+	m := &Manifest{
+		Beta:   Source{Path: "beta.txt"},
+		Volume: Source{Path: "Vol.txt"},
+	}
+	for _, c := range classes.Classes() {
+		m.Classes = append(m.Classes, ClassSource{Name: c.Name, Source: Source{Path: c.File}})
+	}
+	return m
+}
+
+// LoadManifest читает манифест из JSON-файла
+func LoadManifest(filename string) (*Manifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("ошибка разбора манифеста %s: %v", filename, err)
+	}
+	if !filepath.IsAbs(m.DataDir) {
+		m.DataDir = filepath.Join(filepath.Dir(filename), m.DataDir)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("манифест %s: %v", filename, err)
+	}
+	return &m, nil
+}
+
+// Validate проверяет, что заданы все пути и масштабы неотрицательны
+func (m *Manifest) Validate() error {
+	if len(m.Classes) == 0 {
+		return fmt.Errorf("не задано ни одного класса")
+	}
+	check := func(name string, s Source) error {
+		if s.Path == "" {
+			return fmt.Errorf("не задан путь для %s", name)
+		}
+		if s.Scale < 0 {
+			return fmt.Errorf("отрицательный масштаб для %s", name)
+		}
+		if s.Sigma == nil {
+			return nil
+		}
+		if s.Sigma.Path == "" {
+			return fmt.Errorf("не задан путь к погрешностям для %s", name)
+		}
+		if s.Sigma.Scale < 0 {
+			return fmt.Errorf("отрицательный масштаб погрешностей для %s", name)
+		}
+		if s.Sigma.Sigma != nil {
+			return fmt.Errorf("для погрешностей %s нельзя задать погрешности", name)
+		}
+		return nil
+	}
+	if err := check("beta", m.Beta); err != nil {
+		return err
+	}
+	if err := check("volume", m.Volume); err != nil {
+		return err
+	}
+	for _, c := range m.Classes {
+		if err := check("класса "+c.Name, c.Source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolve возвращает путь к файлу с учетом DataDir
+func (m *Manifest) Resolve(path string) string {
+	if filepath.IsAbs(path) || m.DataDir == "" {
+		return path
+	}
+	return filepath.Join(m.DataDir, path)
+}
+
+// Registry строит реестр классов в порядке манифеста с полными путями к файлам
+func (m *Manifest) Registry() (*models.ClassRegistry, error) {
+	classes := make([]models.Class, len(m.Classes))
+	for i, c := range m.Classes {
+		classes[i] = models.Class{Name: c.Name, File: m.Resolve(c.Path), Column: i}
+	}
+	return models.NewClassRegistry(classes...)
+}
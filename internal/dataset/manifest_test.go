@@ -0,0 +1,134 @@
+package dataset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"classification-project/internal/models"
+)
+
+func writeManifest(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLoadManifest проверяет, что относительный data_dir считается от каталога
+// манифеста, а пути к файлам - от data_dir
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "conf", "scene.json")
+	writeManifest(t, filename, `{
+		"data_dir": "../data",
+		"beta": {"path": "beta.nc", "variable": "beta", "row_dim": "alt"},
+		"volume": {"path": "/abs/Vol.txt", "scale": 1e-6, "sigma": {"path": "sVol.txt"}},
+		"classes": [
+			{"name": "u", "path": "u.txt"},
+			{"name": "d", "path": "d.xlsx", "sheet": "dust"}
+		],
+		"missing": ["-999"],
+		"align": {"mode": "intersect", "tolerance": 0.5}
+	}`)
+
+	m, err := LoadManifest(filename)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if want := filepath.Join(dir, "data"); m.DataDir != want {
+		t.Errorf("DataDir = %q, ожидалось %q", m.DataDir, want)
+	}
+	if got, want := m.Resolve(m.Beta.Path), filepath.Join(dir, "data", "beta.nc"); got != want {
+		t.Errorf("Resolve(beta) = %q, ожидалось %q", got, want)
+	}
+	if got := m.Resolve(m.Volume.Path); got != "/abs/Vol.txt" {
+		t.Errorf("абсолютный путь изменен: %q", got)
+	}
+	if m.Beta.Variable != "beta" || m.Beta.RowDim != "alt" || m.Volume.Scale != 1e-6 ||
+		m.Volume.Sigma == nil || m.Volume.Sigma.Path != "sVol.txt" {
+		t.Errorf("источники прочитаны неверно: beta %+v, volume %+v", m.Beta, m.Volume)
+	}
+	if len(m.Missing) != 1 || m.Missing[0] != "-999" || m.Align.Mode != "intersect" || m.Align.Tolerance != 0.5 {
+		t.Errorf("missing %v, align %+v", m.Missing, m.Align)
+	}
+
+	// Порядок классов в манифесте задает столбцы системы
+	reg, err := m.Registry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []models.Class{
+		{Name: "u", File: filepath.Join(dir, "data", "u.txt"), Column: 0},
+		{Name: "d", File: filepath.Join(dir, "data", "d.xlsx"), Column: 1},
+	} {
+		if got := reg.Classes()[i]; got != want {
+			t.Errorf("класс %d: %+v, ожидалось %+v", i, got, want)
+		}
+	}
+	if m.Classes[1].Sheet != "dust" {
+		t.Errorf("лист класса d: %q", m.Classes[1].Sheet)
+	}
+}
+
+// TestLoadManifestDefaultDataDir проверяет, что без data_dir пути считаются
+// от каталога манифеста, а не от текущего каталога
+func TestLoadManifestDefaultDataDir(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "scene.json")
+	writeManifest(t, filename, `{"beta": {"path": "beta.txt"}, "volume": {"path": "Vol.txt"},
+		"classes": [{"name": "d", "path": "d.txt"}]}`)
+	m, err := LoadManifest(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Resolve("d.txt"), filepath.Join(dir, "d.txt"); got != want {
+		t.Errorf("Resolve = %q, ожидалось %q", got, want)
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	valid := func() *Manifest {
+		return &Manifest{
+			Beta:    Source{Path: "beta.txt"},
+			Volume:  Source{Path: "Vol.txt"},
+			Classes: []ClassSource{{Name: "d", Source: Source{Path: "d.txt"}}},
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	for name, edit := range map[string]func(m *Manifest){
+		"без классов":           func(m *Manifest) { m.Classes = nil },
+		"без пути beta":         func(m *Manifest) { m.Beta.Path = "" },
+		"без пути класса":       func(m *Manifest) { m.Classes[0].Path = "" },
+		"отрицательный масштаб": func(m *Manifest) { m.Volume.Scale = -1 },
+		"погрешность без пути":  func(m *Manifest) { m.Beta.Sigma = &Source{} },
+		"масштаб погрешности": func(m *Manifest) {
+			m.Volume.Sigma = &Source{Path: "s.txt", Scale: -2}
+		},
+		"погрешность погрешности": func(m *Manifest) {
+			m.Classes[0].Sigma = &Source{Path: "s.txt", Sigma: &Source{Path: "ss.txt"}}
+		},
+	} {
+		m := valid()
+		edit(m)
+		if err := m.Validate(); err == nil {
+			t.Errorf("%s: ожидалась ошибка", name)
+		}
+	}
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "bad.json")
+	writeManifest(t, filename, `{"beta": {"path": "beta.txt"}, "classes": [{"name": "d", "path": "d.txt"}]}`)
+	if _, err := LoadManifest(filename); err == nil {
+		t.Error("ожидалась ошибка для манифеста без volume")
+	}
+	writeManifest(t, filename, `{"beta": `)
+	if _, err := LoadManifest(filename); err == nil {
+		t.Error("ожидалась ошибка разбора JSON")
+	}
+}
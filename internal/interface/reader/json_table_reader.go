@@ -0,0 +1,38 @@
+package reader
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+
+	"classification-project/internal/models"
+)
+
+// ReadTableFromJSON читает таблицу, сохраненную как models.Table в JSON.
+// Значения, отмеченные в маске как невалидные, заменяются на NaN
+func ReadTableFromJSON(r io.Reader) (*models.Table, error) {
+	var t models.Table
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, errors.New("ошибка разбора JSON: " + err.Error())
+	}
+	if t.Rows <= 0 || t.Columns <= 0 || len(t.Data) != t.Rows*t.Columns {
+		return nil, errors.New("неверный размер таблицы: " + strconv.Itoa(t.Rows) + "x" + strconv.Itoa(t.Columns) +
+			", значений " + strconv.Itoa(len(t.Data)))
+	}
+	if len(t.RowLabels) != t.Rows || len(t.ColumnLabels) != t.Columns {
+		return nil, errors.New("число меток не совпадает с размером таблицы")
+	}
+	if t.Mask != nil {
+		if len(t.Mask) != len(t.Data) {
+			return nil, errors.New("размер маски не совпадает с размером таблицы")
+		}
+		for k, ok := range t.Mask {
+			if !ok {
+				t.Data[k] = math.NaN()
+			}
+		}
+	}
+	return &t, nil
+}
@@ -0,0 +1,557 @@
+package reader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"classification-project/internal/models"
+)
+
+// Типы данных и метки разделов заголовка NetCDF classic (CDF-1/CDF-2)
+const (
+	ncByte   = 1
+	ncChar   = 2
+	ncShort  = 3
+	ncInt    = 4
+	ncFloat  = 5
+	ncDouble = 6
+
+	ncDimension = 0x0A
+	ncVariable  = 0x0B
+	ncAttribute = 0x0C
+
+	ncStreaming = 0xFFFFFFFF
+)
+
+// Стандартные значения заполнения NetCDF для неинициализированных данных
+const (
+	ncFillByte   = -127
+	ncFillShort  = -32767
+	ncFillInt    = -2147483647
+	ncFillFloat  = 9.9692099683868690e+36
+	ncFillDouble = 9.9692099683868690e+36
+)
+
+// ncDim - измерение; Len == 0 у измерения записей (unlimited)
+type ncDim struct {
+	Name string
+	Len  int
+}
+
+// ncAttr - атрибут: строка для NC_CHAR или числа для остальных типов
+type ncAttr struct {
+	Name   string
+	Text   string
+	Values []float64
+}
+
+// ncVar - описание переменной в заголовке
+type ncVar struct {
+	Name   string
+	Dims   []int // индексы измерений
+	Attrs  []ncAttr
+	Type   int
+	VSize  int64
+	Begin  int64
+	record bool
+}
+
+// NetCDF - открытый файл NetCDF classic. Данные читаются по запросу через r
+type NetCDF struct {
+	Version int // 1 - CDF-1 (32-битные смещения), 2 - CDF-2 (64-битные)
+	NumRecs int
+	dims    []ncDim
+	attrs   []ncAttr
+	vars    []ncVar
+	r       io.ReaderAt
+	size    int64 // размер файла в байтах (-1 - неизвестен)
+}
+
+// IsNetCDF проверяет сигнатуру NetCDF classic ("CDF\x01" или "CDF\x02")
+func IsNetCDF(r io.ReaderAt) bool {
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return false
+	}
+	return string(magic[:3]) == "CDF" && (magic[3] == 1 || magic[3] == 2)
+}
+
+// ParseNetCDF разбирает заголовок файла NetCDF classic
+func ParseNetCDF(r io.ReaderAt) (*NetCDF, error) {
+	d := &ncDecoder{r: bufio.NewReader(io.NewSectionReader(r, 0, math.MaxInt64))}
+	magic := d.bytes(4)
+	if d.err != nil || string(magic[:3]) != "CDF" {
+		return nil, errors.New("не файл NetCDF classic")
+	}
+	f := &NetCDF{Version: int(magic[3]), r: r, size: readerSize(r)}
+	if f.Version != 1 && f.Version != 2 {
+		return nil, errors.New("неподдерживаемая версия NetCDF: " + strconv.Itoa(f.Version) + " (поддерживаются CDF-1 и CDF-2)")
+	}
+	numrecs := d.uint32()
+	if numrecs == ncStreaming {
+		return nil, errors.New("NetCDF: потоковое число записей не поддерживается")
+	}
+	f.NumRecs = int(numrecs)
+
+	// Список измерений
+	if n := d.listHeader(ncDimension); n > 0 && d.err == nil {
+		f.dims = make([]ncDim, n)
+		for i := range f.dims {
+			f.dims[i] = ncDim{Name: d.name(), Len: d.count()}
+		}
+	}
+	f.attrs = d.attrList()
+
+	// Список переменных
+	if n := d.listHeader(ncVariable); n > 0 && d.err == nil {
+		f.vars = make([]ncVar, n)
+		for i := range f.vars {
+			v := &f.vars[i]
+			v.Name = d.name()
+			v.Dims = make([]int, min(d.count(), maxHeaderItem))
+			for k := range v.Dims {
+				v.Dims[k] = d.count()
+				if d.err == nil && v.Dims[k] >= len(f.dims) {
+					d.err = errors.New("переменная " + v.Name + ": неверный индекс измерения")
+				}
+			}
+			v.Attrs = d.attrList()
+			v.Type = d.count()
+			v.VSize = int64(d.uint32())
+			if f.Version == 1 {
+				v.Begin = int64(d.uint32())
+			} else {
+				v.Begin = int64(d.uint64())
+			}
+			v.record = d.err == nil && len(v.Dims) > 0 && f.dims[v.Dims[0]].Len == 0
+		}
+	}
+	if d.err != nil {
+		return nil, errors.New("ошибка разбора заголовка NetCDF: " + d.err.Error())
+	}
+	return f, nil
+}
+
+// Variables возвращает имена всех переменных файла
+func (f *NetCDF) Variables() []string {
+	names := make([]string, len(f.vars))
+	for i, v := range f.vars {
+		names[i] = v.Name
+	}
+	return names
+}
+
+// ReadTable читает двумерную переменную name как таблицу. Метками строк и
+// столбцов служат значения координатных переменных (одномерных переменных
+// с именем измерения), а при их отсутствии - номера от 0. rowDim задает
+// измерение строк таблицы ("" - первое измерение переменной); если это второе
+// измерение, таблица транспонируется. Значения, равные _FillValue или
+// missing_value, отмечаются пропусками; scale_factor и add_offset применяются
+func (f *NetCDF) ReadTable(name, rowDim string) (*models.Table, error) {
+	v := f.variable(name)
+	if v == nil {
+		return nil, errors.New("нет переменной " + strconv.Quote(name) + ", доступны: " + strings.Join(f.Variables(), ", "))
+	}
+	if len(v.Dims) != 2 {
+		return nil, errors.New("переменная " + name + " должна быть двумерной, измерений: " + strconv.Itoa(len(v.Dims)))
+	}
+	if v.Type == ncChar {
+		return nil, errors.New("переменная " + name + " текстовая, ожидаются числа")
+	}
+	values, err := f.values(v)
+	if err != nil {
+		return nil, err
+	}
+
+	rowAxis, colAxis := 0, 1
+	switch rowDim {
+	case "", f.dims[v.Dims[0]].Name:
+	case f.dims[v.Dims[1]].Name:
+		rowAxis, colAxis = 1, 0
+	default:
+		return nil, errors.New("у переменной " + name + " нет измерения " + strconv.Quote(rowDim))
+	}
+	shape := []int{f.dimLen(v.Dims[0]), f.dimLen(v.Dims[1])}
+	rows, cols := shape[rowAxis], shape[colAxis]
+	if rows == 0 || cols == 0 {
+		return nil, errors.New("переменная " + name + " не содержит данных")
+	}
+	rowLabels, err := f.coordinateLabels(v.Dims[rowAxis])
+	if err != nil {
+		return nil, err
+	}
+	colLabels, err := f.coordinateLabels(v.Dims[colAxis])
+	if err != nil {
+		return nil, err
+	}
+
+	fill := fillValues(v)
+	scale, offset := 1.0, 0.0
+	if a := v.attr("scale_factor"); a != nil && len(a.Values) > 0 {
+		scale = a.Values[0]
+	}
+	if a := v.attr("add_offset"); a != nil && len(a.Values) > 0 {
+		offset = a.Values[0]
+	}
+
+	table := models.NewTable(rows, cols, nil, colLabels, rowLabels)
+	for i := range rows {
+		for j := range cols {
+			k := i*cols + j
+			if rowAxis == 1 {
+				k = j*rows + i
+			}
+			raw := values[k]
+			if isFill(raw, fill) {
+				table.SetMissing(i, j)
+				continue
+			}
+			table.Set(i, j, raw*scale+offset)
+		}
+	}
+	return table, nil
+}
+
+// ReadNetCDFTable читает двумерную переменную из файла NetCDF (см. NetCDF.ReadTable)
+func ReadNetCDFTable(filename, variable, rowDim string) (*models.Table, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f, err := ParseNetCDF(file)
+	if err != nil {
+		return nil, err
+	}
+	return f.ReadTable(variable, rowDim)
+}
+
+func (f *NetCDF) variable(name string) *ncVar {
+	for i := range f.vars {
+		if f.vars[i].Name == name {
+			return &f.vars[i]
+		}
+	}
+	return nil
+}
+
+// dimLen возвращает длину измерения; для измерения записей - число записей
+func (f *NetCDF) dimLen(dim int) int {
+	if f.dims[dim].Len == 0 {
+		return f.NumRecs
+	}
+	return f.dims[dim].Len
+}
+
+// coordinateLabels возвращает метки для измерения: значения координатной
+// переменной (числовой одномерной или текстовой [dim, strlen]) или номера
+func (f *NetCDF) coordinateLabels(dim int) ([]string, error) {
+	n := f.dimLen(dim)
+	labels := make([]string, n)
+	v := f.variable(f.dims[dim].Name)
+	if v == nil || len(v.Dims) == 0 || v.Dims[0] != dim || (len(v.Dims) > 1 && v.Type != ncChar) || len(v.Dims) > 2 {
+		for i := range labels {
+			labels[i] = strconv.Itoa(i)
+		}
+		return labels, nil
+	}
+	if v.Type == ncChar {
+		raw, err := f.data(v)
+		if err != nil {
+			return nil, err
+		}
+		width := 1
+		if len(v.Dims) == 2 {
+			width = f.dimLen(v.Dims[1])
+		}
+		for i := range labels {
+			labels[i] = strings.TrimRight(string(raw[i*width:(i+1)*width]), "\x00 ")
+		}
+		return labels, nil
+	}
+	values, err := f.values(v)
+	if err != nil {
+		return nil, err
+	}
+	for i := range labels {
+		labels[i] = strconv.FormatFloat(values[i], 'g', -1, 64)
+	}
+	return labels, nil
+}
+
+// values читает все значения числовой переменной в порядке хранения
+func (f *NetCDF) values(v *ncVar) ([]float64, error) {
+	raw, err := f.data(v)
+	if err != nil {
+		return nil, err
+	}
+	size := typeSize(v.Type)
+	values := make([]float64, len(raw)/size)
+	for i := range values {
+		values[i] = v.decode(raw[i*size:])
+	}
+	return values, nil
+}
+
+// data читает байты переменной; для переменных с измерением записей
+// данные собираются из всех записей
+func (f *NetCDF) data(v *ncVar) ([]byte, error) {
+	size := typeSize(v.Type)
+	if size == 0 {
+		return nil, errors.New("переменная " + v.Name + ": неизвестный тип " + strconv.Itoa(v.Type))
+	}
+	// Размеры из заголовка проверяются до выделения памяти: поврежденный
+	// файл не должен приводить к огромным выделениям
+	limit := f.size
+	if limit < 0 {
+		limit = maxVariableSize
+	}
+	tooLarge := errors.New("переменная " + v.Name + ": данные выходят за пределы файла")
+	chunk := int64(size)
+	for _, d := range v.Dims {
+		if n := int64(f.dims[d].Len); n != 0 {
+			if chunk > limit/n {
+				return nil, tooLarge
+			}
+			chunk *= n
+		}
+	}
+	if v.Begin < 0 || v.Begin > limit-chunk {
+		return nil, tooLarge
+	}
+	if !v.record {
+		buf := make([]byte, chunk)
+		if _, err := f.r.ReadAt(buf, v.Begin); err != nil {
+			return nil, errors.New("переменная " + v.Name + ": " + err.Error())
+		}
+		return buf, nil
+	}
+
+	// Записи следуют друг за другом, в каждой - по фрагменту всех переменных
+	// записей. При единственной такой переменной записи не выравниваются
+	var recSize int64
+	nRecVars := 0
+	for _, rv := range f.vars {
+		if rv.record {
+			recSize += rv.VSize
+			nRecVars++
+		}
+	}
+	if nRecVars == 1 {
+		recSize = chunk
+	}
+	if recs := int64(f.NumRecs); recs > 1 && (recSize <= 0 || recs-1 > (limit-v.Begin-chunk)/recSize) {
+		return nil, tooLarge
+	}
+	buf := make([]byte, chunk*int64(f.NumRecs))
+	for rec := range f.NumRecs {
+		if _, err := f.r.ReadAt(buf[int64(rec)*chunk:int64(rec+1)*chunk], v.Begin+int64(rec)*recSize); err != nil {
+			return nil, errors.New("переменная " + v.Name + ", запись " + strconv.Itoa(rec) + ": " + err.Error())
+		}
+	}
+	return buf, nil
+}
+
+func (v *ncVar) attr(name string) *ncAttr {
+	for i := range v.Attrs {
+		if v.Attrs[i].Name == name {
+			return &v.Attrs[i]
+		}
+	}
+	return nil
+}
+
+// fillValues возвращает значения, означающие пропуск: _FillValue,
+// missing_value, а при отсутствии _FillValue - стандартное значение заполнения
+func fillValues(v *ncVar) []float64 {
+	var fill []float64
+	if a := v.attr("_FillValue"); a != nil {
+		fill = append(fill, a.Values...)
+	} else {
+		switch v.Type {
+		case ncByte:
+			fill = append(fill, ncFillByte)
+		case ncShort:
+			fill = append(fill, ncFillShort)
+		case ncInt:
+			fill = append(fill, ncFillInt)
+		case ncFloat:
+			fill = append(fill, float64(float32(ncFillFloat)))
+		case ncDouble:
+			fill = append(fill, ncFillDouble)
+		}
+	}
+	if a := v.attr("missing_value"); a != nil {
+		fill = append(fill, a.Values...)
+	}
+	return fill
+}
+
+// maxVariableSize ограничивает размер данных переменной, если размер файла
+// неизвестен
+const maxVariableSize = 1 << 32
+
+// readerSize возвращает размер данных r или -1, если его нельзя узнать
+func readerSize(r io.ReaderAt) int64 {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size()
+	case *os.File:
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+func isFill(x float64, fill []float64) bool {
+	if math.IsNaN(x) {
+		return true
+	}
+	for _, f := range fill {
+		if x == f {
+			return true
+		}
+	}
+	return false
+}
+
+func typeSize(t int) int {
+	switch t {
+	case ncByte, ncChar:
+		return 1
+	case ncShort:
+		return 2
+	case ncInt, ncFloat:
+		return 4
+	case ncDouble:
+		return 8
+	}
+	return 0
+}
+
+// ncDecoder последовательно читает заголовок; первая ошибка сохраняется в err,
+// после нее все методы возвращают нулевые значения
+type ncDecoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *ncDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return make([]byte, n)
+	}
+	buf := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, buf)
+	return buf
+}
+
+func (d *ncDecoder) uint32() uint32 {
+	return binary.BigEndian.Uint32(d.bytes(4))
+}
+
+func (d *ncDecoder) uint64() uint64 {
+	return binary.BigEndian.Uint64(d.bytes(8))
+}
+
+// count читает неотрицательное 32-битное число
+func (d *ncDecoder) count() int {
+	n := int32(d.uint32())
+	if n < 0 && d.err == nil {
+		d.err = errors.New("отрицательное число элементов")
+	}
+	return int(max(n, 0))
+}
+
+// maxHeaderItem ограничивает размер имени или атрибута в заголовке,
+// чтобы поврежденный файл не приводил к огромным выделениям памяти
+const maxHeaderItem = 1 << 24
+
+// padded читает n байт и пропускает выравнивание до 4 байт
+func (d *ncDecoder) padded(n int) []byte {
+	if n > maxHeaderItem && d.err == nil {
+		d.err = errors.New("слишком большой элемент заголовка: " + strconv.Itoa(n) + " байт")
+	}
+	if d.err != nil {
+		return nil
+	}
+	buf := d.bytes(n)
+	d.bytes((4 - n%4) % 4)
+	return buf
+}
+
+func (d *ncDecoder) name() string {
+	return string(d.padded(d.count()))
+}
+
+// listHeader читает метку списка и число элементов; ABSENT - два нуля
+func (d *ncDecoder) listHeader(tag uint32) int {
+	t := d.uint32()
+	n := d.count()
+	if n > maxHeaderItem && d.err == nil {
+		d.err = errors.New("слишком длинный список в заголовке: " + strconv.Itoa(n))
+	}
+	if t != tag && (t != 0 || n != 0) && d.err == nil {
+		d.err = errors.New("неожиданная метка раздела 0x" + strconv.FormatUint(uint64(t), 16))
+	}
+	return n
+}
+
+func (d *ncDecoder) attrList() []ncAttr {
+	n := d.listHeader(ncAttribute)
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	n = min(n, maxHeaderItem)
+	attrs := make([]ncAttr, n)
+	for i := range attrs {
+		a := &attrs[i]
+		a.Name = d.name()
+		typ := d.count()
+		count := d.count()
+		size := typeSize(typ)
+		if size == 0 {
+			if d.err == nil {
+				d.err = errors.New("атрибут " + a.Name + ": неизвестный тип " + strconv.Itoa(typ))
+			}
+			return nil
+		}
+		raw := d.padded(count * size)
+		if d.err != nil {
+			return nil
+		}
+		if typ == ncChar {
+			a.Text = strings.TrimRight(string(raw), "\x00")
+			continue
+		}
+		v := &ncVar{Type: typ}
+		a.Values = make([]float64, count)
+		for k := range a.Values {
+			a.Values[k] = v.decode(raw[k*size:])
+		}
+	}
+	return attrs
+}
+
+// decode разбирает одно значение типа переменной
+func (v *ncVar) decode(b []byte) float64 {
+	switch v.Type {
+	case ncByte:
+		return float64(int8(b[0]))
+	case ncShort:
+		return float64(int16(binary.BigEndian.Uint16(b)))
+	case ncInt:
+		return float64(int32(binary.BigEndian.Uint32(b)))
+	case ncFloat:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case ncDouble:
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	return math.NaN()
+}
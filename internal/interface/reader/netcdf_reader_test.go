@@ -0,0 +1,294 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// ncTestAttr - атрибут тестового файла: тип и значения в big-endian
+type ncTestAttr struct {
+	name  string
+	typ   int
+	count int
+	data  []byte
+}
+
+// ncTestVar - переменная тестового файла
+type ncTestVar struct {
+	name  string
+	dims  []int
+	attrs []ncTestAttr
+	typ   int
+	data  []byte // для переменных записей - данные одной записи подряд для всех записей
+}
+
+// buildNetCDF собирает файл NetCDF classic заданной версии. Измерение 0 -
+// измерение записей, если dims[0] == 0; данные переменных записей чередуются
+// по записям, как того требует формат
+func buildNetCDF(version byte, numrecs int, dimNames []string, dims []int, vars []ncTestVar) []byte {
+	be := binary.BigEndian
+	pad := func(b *bytes.Buffer) {
+		for b.Len()%4 != 0 {
+			b.WriteByte(0)
+		}
+	}
+	name := func(b *bytes.Buffer, s string) {
+		binary.Write(b, be, int32(len(s)))
+		b.WriteString(s)
+		pad(b)
+	}
+	isRecord := func(v ncTestVar) bool { return len(v.dims) > 0 && dims[v.dims[0]] == 0 }
+	vsize := func(v ncTestVar) int {
+		n := len(v.data)
+		if isRecord(v) {
+			n /= numrecs
+		}
+		return (n + 3) / 4 * 4
+	}
+	header := func(begins []int64) []byte {
+		var b bytes.Buffer
+		b.WriteString("CDF")
+		b.WriteByte(version)
+		binary.Write(&b, be, int32(numrecs))
+		binary.Write(&b, be, int32(ncDimension))
+		binary.Write(&b, be, int32(len(dims)))
+		for i, d := range dims {
+			name(&b, dimNames[i])
+			binary.Write(&b, be, int32(d))
+		}
+		binary.Write(&b, be, [2]int32{}) // глобальных атрибутов нет
+		binary.Write(&b, be, int32(ncVariable))
+		binary.Write(&b, be, int32(len(vars)))
+		for i, v := range vars {
+			name(&b, v.name)
+			binary.Write(&b, be, int32(len(v.dims)))
+			for _, d := range v.dims {
+				binary.Write(&b, be, int32(d))
+			}
+			if len(v.attrs) == 0 {
+				binary.Write(&b, be, [2]int32{})
+			} else {
+				binary.Write(&b, be, int32(ncAttribute))
+				binary.Write(&b, be, int32(len(v.attrs)))
+				for _, a := range v.attrs {
+					name(&b, a.name)
+					binary.Write(&b, be, int32(a.typ))
+					binary.Write(&b, be, int32(a.count))
+					b.Write(a.data)
+					pad(&b)
+				}
+			}
+			binary.Write(&b, be, int32(v.typ))
+			binary.Write(&b, be, int32(vsize(v)))
+			if version == 1 {
+				binary.Write(&b, be, int32(begins[i]))
+			} else {
+				binary.Write(&b, be, begins[i])
+			}
+		}
+		return b.Bytes()
+	}
+
+	// Размер заголовка не зависит от смещений
+	begins := make([]int64, len(vars))
+	offset := int64(len(header(begins)))
+	recSize := int64(0)
+	for i, v := range vars {
+		if !isRecord(v) {
+			begins[i] = offset
+			offset += int64(vsize(v))
+		}
+	}
+	for i, v := range vars {
+		if isRecord(v) {
+			begins[i] = offset + recSize
+			recSize += int64(vsize(v))
+		}
+	}
+
+	var b bytes.Buffer
+	b.Write(header(begins))
+	for _, v := range vars {
+		if !isRecord(v) {
+			b.Write(v.data)
+			pad(&b)
+		}
+	}
+	for rec := range numrecs {
+		for _, v := range vars {
+			if isRecord(v) {
+				n := len(v.data) / numrecs
+				b.Write(v.data[rec*n : (rec+1)*n])
+				pad(&b)
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+func encode(values ...any) []byte {
+	var b bytes.Buffer
+	for _, v := range values {
+		binary.Write(&b, binary.BigEndian, v)
+	}
+	return b.Bytes()
+}
+
+func TestReadNetCDFTable(t *testing.T) {
+	// Измерения: time (записи, 2 записи), altitude (3), site (2, без координаты)
+	dimNames := []string{"time", "altitude", "site"}
+	dims := []int{0, 3, 2}
+	vars := []ncTestVar{
+		{name: "altitude", dims: []int{1}, typ: ncDouble, data: encode(1005.0, 1012.5, 1020.0)},
+		{
+			name: "frac", dims: []int{1, 2}, typ: ncShort,
+			attrs: []ncTestAttr{{name: "scale_factor", typ: ncDouble, count: 1, data: encode(0.5)}},
+			data:  encode(int16(1), int16(2), int16(3), int16(4), int16(5), int16(6)),
+		},
+		{name: "time", dims: []int{0}, typ: ncInt, data: encode(int32(100), int32(200))},
+		{
+			name: "beta", dims: []int{0, 1}, typ: ncFloat,
+			attrs: []ncTestAttr{
+				{name: "units", typ: ncChar, count: 7, data: []byte("1/Mm/sr")},
+				{name: "_FillValue", typ: ncFloat, count: 1, data: encode(float32(-1))},
+			},
+			data: encode(float32(0.25), float32(0.5), float32(-1), float32(1.25), float32(1.5), float32(1.75)),
+		},
+	}
+
+	for _, version := range []byte{1, 2} {
+		t.Run("CDF-"+string('0'+version), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scene.nc")
+			if err := os.WriteFile(path, buildNetCDF(version, 2, dimNames, dims, vars), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			frac, err := ReadTableFromFileWithOptions(path, Options{Variable: "frac"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"1005", "1012.5", "1020"}; !reflect.DeepEqual(frac.RowLabels, want) {
+				t.Errorf("метки строк frac: %q, ожидалось %q", frac.RowLabels, want)
+			}
+			if want := []string{"0", "1"}; !reflect.DeepEqual(frac.ColumnLabels, want) {
+				t.Errorf("метки столбцов frac: %q, ожидалось %q", frac.ColumnLabels, want)
+			}
+			if want := []float64{0.5, 1, 1.5, 2, 2.5, 3}; !reflect.DeepEqual(frac.Data, want) {
+				t.Errorf("данные frac: %v, ожидалось %v", frac.Data, want)
+			}
+
+			// Переменная записей (time, altitude), транспонированная к строкам-высотам
+			beta, err := ReadTableFromFileWithOptions(path, Options{Variable: "beta", RowDim: "altitude"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if beta.Rows != 3 || beta.Columns != 2 {
+				t.Fatalf("размер beta %dx%d, ожидалось 3x2", beta.Rows, beta.Columns)
+			}
+			if want := []string{"100", "200"}; !reflect.DeepEqual(beta.ColumnLabels, want) {
+				t.Errorf("метки столбцов beta: %q, ожидалось %q", beta.ColumnLabels, want)
+			}
+			want := []float64{0.25, 1.25, 0.5, 1.5, math.NaN(), 1.75}
+			for k, w := range want {
+				i, j := k/2, k%2
+				if math.IsNaN(w) {
+					if beta.Valid(i, j) {
+						t.Errorf("beta(%d,%d) должно быть пропуском", i, j)
+					}
+				} else if beta.Get(i, j) != w {
+					t.Errorf("beta(%d,%d) = %v, ожидалось %v", i, j, beta.Get(i, j), w)
+				}
+			}
+
+			if _, err := ReadTableFromFileWithOptions(path, Options{Variable: "altitude"}); err == nil {
+				t.Error("ожидалась ошибка для одномерной переменной")
+			}
+			if _, err := ReadTableFromFileWithOptions(path, Options{}); err == nil {
+				t.Error("ожидалась ошибка без имени переменной")
+			}
+		})
+	}
+}
+
+// TestNetCDFIntegerFill проверяет стандартные значения заполнения целых типов
+// для переменных без _FillValue
+func TestNetCDFIntegerFill(t *testing.T) {
+	dimNames := []string{"altitude", "site"}
+	dims := []int{2, 2}
+	vars := []ncTestVar{
+		{name: "b", dims: []int{0, 1}, typ: ncByte, data: encode(int8(1), int8(-127), int8(-128), int8(4))},
+		{name: "s", dims: []int{0, 1}, typ: ncShort, data: encode(int16(1), int16(-32767), int16(-32768), int16(4))},
+		{name: "i", dims: []int{0, 1}, typ: ncInt, data: encode(int32(1), int32(-2147483647), int32(-2147483648), int32(4))},
+	}
+	nc, err := ParseNetCDF(bytes.NewReader(buildNetCDF(1, 0, dimNames, dims, vars)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vars {
+		table, err := nc.ReadTable(v.name, "")
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		if table.Valid(0, 1) {
+			t.Errorf("%s: значение заполнения не отмечено пропуском", v.name)
+		}
+		// Минимальное значение типа - обычные данные, а не пропуск
+		if !table.Valid(1, 0) || !table.Valid(0, 0) || table.Get(1, 1) != 4 {
+			t.Errorf("%s: данные прочитаны неверно: %v", v.name, table.Data)
+		}
+	}
+}
+
+// onlyReaderAt скрывает размер данных
+type onlyReaderAt struct{ r *bytes.Reader }
+
+func (o onlyReaderAt) ReadAt(p []byte, off int64) (int, error) { return o.r.ReadAt(p, off) }
+
+// TestNetCDFCorruptSizes проверяет, что размеры из заголовка, превышающие
+// размер файла, дают ошибку до выделения памяти
+func TestNetCDFCorruptSizes(t *testing.T) {
+	vars := []ncTestVar{
+		{name: "frac", dims: []int{0, 1}, typ: ncDouble, data: encode(1.0, 2.0, 3.0, 4.0, 5.0, 6.0)},
+	}
+	data := buildNetCDF(2, 0, []string{"altitude", "site"}, []int{3, 2}, vars)
+
+	for name, corrupt := range map[string]func(f *NetCDF){
+		"длина измерения":        func(f *NetCDF) { f.dims[1].Len = 1 << 30 },
+		"переполнение":           func(f *NetCDF) { f.dims[0].Len, f.dims[1].Len = 1<<31-1, 1<<31-1 },
+		"смещение":               func(f *NetCDF) { f.vars[0].Begin = int64(len(data)) - 8 },
+		"отрицательное смещение": func(f *NetCDF) { f.vars[0].Begin = -1 },
+	} {
+		for _, r := range []io.ReaderAt{bytes.NewReader(data), onlyReaderAt{bytes.NewReader(data)}} {
+			nc, err := ParseNetCDF(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := nc.ReadTable("frac", ""); err != nil {
+				t.Fatalf("%s: исходный файл не читается: %v", name, err)
+			}
+			corrupt(nc)
+			if _, err := nc.ReadTable("frac", ""); err == nil {
+				t.Errorf("%s (размер %d): ожидалась ошибка", name, nc.size)
+			}
+		}
+	}
+
+	// Переменная записей с огромным числом записей
+	recVars := []ncTestVar{
+		{name: "frac", dims: []int{0, 1}, typ: ncFloat, data: encode(float32(1), float32(2), float32(3), float32(4))},
+	}
+	nc, err := ParseNetCDF(bytes.NewReader(buildNetCDF(1, 2, []string{"time", "site"}, []int{0, 2}, recVars)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc.NumRecs = 1 << 30
+	if _, err := nc.ReadTable("frac", ""); err == nil {
+		t.Error("ожидалась ошибка для числа записей больше размера файла")
+	}
+}
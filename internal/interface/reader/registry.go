@@ -0,0 +1,235 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"classification-project/internal/models"
+)
+
+// TableReader читает таблицу одного формата
+type TableReader interface {
+	ReadTable(in *Input, opts Options) (*models.Table, error)
+}
+
+// TableReaderFunc позволяет использовать функцию как TableReader
+type TableReaderFunc func(in *Input, opts Options) (*models.Table, error)
+
+func (f TableReaderFunc) ReadTable(in *Input, opts Options) (*models.Table, error) {
+	return f(in, opts)
+}
+
+// Input - открытый источник таблицы
+type Input struct {
+	*bufio.Reader // данные (уже распакованные, если источник сжат gzip)
+	// Name - имя файла без суффикса .gz (может быть пустым)
+	Name string
+	// At - произвольный доступ к данным; nil для сжатых и потоковых источников
+	At io.ReaderAt
+	// Size - размер данных в байтах или его оценка (0 - неизвестен)
+	Size int64
+}
+
+// ReaderAt возвращает данные с произвольным доступом. Если источник
+// потоковый, данные целиком читаются в память
+func (in *Input) ReaderAt() (io.ReaderAt, error) {
+	if in.At != nil {
+		return in.At, nil
+	}
+	data, err := io.ReadAll(in.Reader)
+	if err != nil {
+		return nil, err
+	}
+	in.At, in.Size = bytes.NewReader(data), int64(len(data))
+	return in.At, nil
+}
+
+// sniffSize - число первых байт, доступных функции Sniff
+const sniffSize = 512
+
+// Format описывает формат таблиц для реестра
+type Format struct {
+	Name string
+	// Extensions - расширения файлов вместе с точкой, например ".csv"
+	Extensions []string
+	// Sniff распознает формат по первым байтам данных (nil - только по расширению)
+	Sniff  func(head []byte) bool
+	Reader TableReader
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   []Format
+)
+
+// Register добавляет формат в реестр. Формат, зарегистрированный позже,
+// проверяется раньше, поэтому можно переопределить встроенный формат для
+// расширения. Register паникует при пустом имени, повторном имени или nil Reader
+func Register(f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if f.Name == "" || f.Reader == nil {
+		panic("reader: формат без имени или без Reader")
+	}
+	for _, g := range formats {
+		if g.Name == f.Name {
+			panic("reader: формат " + f.Name + " уже зарегистрирован")
+		}
+	}
+	formats = append(formats, f)
+}
+
+// Formats возвращает имена зарегистрированных форматов по алфавиту
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// detect выбирает формат: сначала по содержимому, затем по расширению,
+// иначе - текстовая таблица
+func detect(name string, head []byte) Format {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	for i := len(formats) - 1; i >= 0; i-- {
+		if formats[i].Sniff != nil && formats[i].Sniff(head) {
+			return formats[i]
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for i := len(formats) - 1; i >= 0; i-- {
+		for _, e := range formats[i].Extensions {
+			if ext != "" && strings.EqualFold(e, ext) {
+				return formats[i]
+			}
+		}
+	}
+	for _, f := range formats {
+		if f.Name == FormatText {
+			return f
+		}
+	}
+	panic("reader: не зарегистрирован текстовый формат")
+}
+
+// Open читает таблицу из файла любого зарегистрированного формата.
+// Формат определяется по содержимому или расширению, файлы gzip
+// распаковываются автоматически
+func Open(filename string, opts Options) (*models.Table, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	in := &Input{Name: filename, At: file}
+	if info, err := file.Stat(); err == nil {
+		in.Size = info.Size()
+	}
+	br := bufio.NewReaderSize(file, readBufferSize)
+	if isGzip(br) {
+		in.Name = trimGz(filename)
+		in.At, in.Size = nil, gzipSize(file, in.Size)
+	}
+	r, err := decompress(br)
+	if err != nil {
+		return nil, err
+	}
+	if in.At == nil {
+		br = bufio.NewReaderSize(r, readBufferSize)
+	}
+	in.Reader = br
+	return read(in, opts)
+}
+
+// Decode читает таблицу из потока r; name используется только для
+// определения формата по расширению и может быть пустым
+func Decode(r io.Reader, name string, opts Options) (*models.Table, error) {
+	r, err := decompress(bufio.NewReaderSize(r, readBufferSize))
+	if err != nil {
+		return nil, err
+	}
+	name = trimGz(name)
+	return read(&Input{Reader: bufio.NewReaderSize(r, readBufferSize), Name: name}, opts)
+}
+
+// trimGz отбрасывает расширение .gz, чтобы формат определялся по внутреннему
+func trimGz(name string) string {
+	if strings.EqualFold(filepath.Ext(name), ".gz") {
+		return name[:len(name)-3]
+	}
+	return name
+}
+
+func read(in *Input, opts Options) (*models.Table, error) {
+	head, err := in.Peek(sniffSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	return detect(in.Name, head).Reader.ReadTable(in, opts)
+}
+
+// Имена встроенных форматов
+const (
+	FormatText   = "txt"    // текстовая таблица с метками (по умолчанию)
+	FormatJSON   = "json"   // models.Table в JSON
+	FormatNetCDF = "netcdf" // NetCDF classic, переменная Options.Variable
+	FormatMatrix = "matrix" // матрица без меток с размерами в первой строке
+)
+
+func init() {
+	Register(Format{
+		Name:       FormatText,
+		Extensions: []string{".txt", ".csv", ".tsv", ".dat"},
+		Reader: TableReaderFunc(func(in *Input, opts Options) (*models.Table, error) {
+			return readText(in, opts, in.Size)
+		}),
+	})
+	Register(Format{
+		Name:       FormatJSON,
+		Extensions: []string{".json"},
+		Reader: TableReaderFunc(func(in *Input, opts Options) (*models.Table, error) {
+			return ReadTableFromJSON(in)
+		}),
+	})
+	Register(Format{
+		Name:       FormatMatrix,
+		Extensions: []string{".matrix"},
+		Reader: TableReaderFunc(func(in *Input, opts Options) (*models.Table, error) {
+			return NewTXTMatrixReader(in).ReadTable()
+		}),
+	})
+	Register(Format{
+		Name:       FormatNetCDF,
+		Extensions: []string{".nc", ".cdf"},
+		Sniff: func(head []byte) bool {
+			return IsNetCDF(bytes.NewReader(head))
+		},
+		Reader: TableReaderFunc(func(in *Input, opts Options) (*models.Table, error) {
+			if opts.Variable == "" {
+				return nil, errors.New("для файла NetCDF не задано имя переменной")
+			}
+			at, err := in.ReaderAt()
+			if err != nil {
+				return nil, err
+			}
+			nc, err := ParseNetCDF(at)
+			if err != nil {
+				return nil, err
+			}
+			return nc.ReadTable(opts.Variable, opts.RowDim)
+		}),
+	})
+}
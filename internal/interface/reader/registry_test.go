@@ -0,0 +1,92 @@
+package reader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"classification-project/internal/models"
+)
+
+func TestOpenFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"table.txt":    "\"A\"\t\"B\"\n\"1\"\t0.5\t1.5\n",
+		"table.csv":    ",A,B\n1,0.5,1.5\n",
+		"table.json":   `{"rows":1,"columns":2,"data":[0.5,1.5],"column_labels":["A","B"],"row_labels":["1"]}`,
+		"table.matrix": "1 2\n0.5\n1.5\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		table, err := Open(filepath.Join(dir, name), Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(table.Data, []float64{0.5, 1.5}) {
+			t.Errorf("%s: данные %v", name, table.Data)
+		}
+	}
+}
+
+// Сторонний формат: строки "метка=значение" через запятую, одна строка таблицы.
+// Распознается по сигнатуре "KV:" независимо от расширения
+func TestRegisterFormat(t *testing.T) {
+	Register(Format{
+		Name:       "test-kv",
+		Extensions: []string{".kv"},
+		Sniff:      func(head []byte) bool { return bytes.HasPrefix(head, []byte("KV:")) },
+		Reader: TableReaderFunc(func(in *Input, opts Options) (*models.Table, error) {
+			line, _ := in.ReadString('\n')
+			var labels []string
+			var data []float64
+			for _, kv := range strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "KV:")), ",") {
+				label, value, _ := strings.Cut(kv, "=")
+				labels = append(labels, label)
+				data = append(data, float64(len(value)))
+			}
+			return models.NewTable(1, len(labels), data, labels, []string{"0"}), nil
+		}),
+	})
+
+	path := filepath.Join(t.TempDir(), "scene.txt")
+	if err := os.WriteFile(path, []byte("KV:a=x,b=yyy\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	table, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.ColumnLabels, []string{"a", "b"}) || !reflect.DeepEqual(table.Data, []float64{1, 3}) {
+		t.Errorf("таблица стороннего формата: %+v", table)
+	}
+
+	found := false
+	for _, name := range Formats() {
+		found = found || name == "test-kv"
+	}
+	if !found {
+		t.Error("формат не найден в реестре")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("повторная регистрация должна паниковать")
+		}
+	}()
+	Register(Format{Name: "test-kv", Reader: TableReaderFunc(nil)})
+}
+
+func TestDecodeStream(t *testing.T) {
+	table, err := Decode(strings.NewReader(`{"rows":1,"columns":1,"data":[2],"column_labels":["A"],"row_labels":["1"]}`), "x.json", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Get(0, 0) != 2 {
+		t.Errorf("значение %v", table.Get(0, 0))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"classification-project/internal/models"
 	"fmt"
 	"io"
+	"strconv"
 )
 
 type TXTMatrixReader struct {
@@ -26,7 +27,7 @@ func (r *TXTMatrixReader) ReadMatrix() (*models.MatrixData, error) {
 	data = make([]float64, rows*columns)
 	for i := range rows {
 		for j := range columns {
-			if _, err = fmt.Fscanf(r.reader, "%f ", &data[i*columns+j]); err != nil {
+			if _, err = fmt.Fscan(r.reader, &data[i*columns+j]); err != nil {
 				return nil, err
 			}
 		}
@@ -34,3 +35,20 @@ func (r *TXTMatrixReader) ReadMatrix() (*models.MatrixData, error) {
 
 	return models.NewMatrix(rows, columns, data), nil
 }
+
+// ReadTable читает матрицу как таблицу; метками строк и столбцов служат номера от 0
+func (r *TXTMatrixReader) ReadTable() (*models.Table, error) {
+	m, err := r.ReadMatrix()
+	if err != nil {
+		return nil, err
+	}
+	return models.NewTable(m.Rows, m.Columns, m.Data, indexLabels(m.Columns), indexLabels(m.Rows)), nil
+}
+
+func indexLabels(n int) []string {
+	labels := make([]string, n)
+	for i := range labels {
+		labels[i] = strconv.Itoa(i)
+	}
+	return labels
+}
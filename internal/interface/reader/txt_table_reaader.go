@@ -2,7 +2,11 @@ package reader
 
 import (
 	"bufio"
+	"compress/gzip"
+	"encoding/binary"
 	"errors"
+	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -10,96 +14,397 @@ import (
 	"classification-project/internal/models"
 )
 
-// ReadTableFromFile читает таблицу из текстового файла и возвращает *models.Table
+// whitespace - признак разделения полей любым количеством пробелов и табуляций
+const whitespace = 0
+
+// DefaultMissingValues - стандартные обозначения пропущенных значений.
+// Пустое поле возможно только при явном разделителе (табуляция, ';', ',')
+var DefaultMissingValues = []string{"NaN", "NA", "-9999", ""}
+
+// Options - параметры чтения таблицы
+type Options struct {
+	// MissingValues - обозначения пропущенных значений (nil - DefaultMissingValues).
+	// Числовые обозначения сравниваются по значению, т.е. "-9999" совпадает с "-9999.0"
+	MissingValues []string
+	// Variable - имя двумерной переменной в файле NetCDF (для текстовых таблиц не используется)
+	Variable string
+	// RowDim - измерение NetCDF для строк таблицы ("" - первое измерение переменной)
+	RowDim string
+	// Sheet - имя листа книги xlsx ("" - первый лист)
+	Sheet string
+}
+
+// missingSet - обозначения пропусков, разобранные один раз перед чтением
+type missingSet struct {
+	text    []string  // сравниваются как строки без учета регистра
+	numbers []float64 // сравниваются по значению
+}
+
+func (o Options) missingSet() missingSet {
+	markers := o.MissingValues
+	if markers == nil {
+		markers = DefaultMissingValues
+	}
+	var ms missingSet
+	for _, m := range markers {
+		if v, err := strconv.ParseFloat(m, 64); err == nil && !math.IsNaN(v) {
+			ms.numbers = append(ms.numbers, v)
+		} else {
+			ms.text = append(ms.text, m)
+		}
+	}
+	return ms
+}
+
+// parse разбирает значение поля; ok == false означает пропуск
+func (ms missingSet) parse(field string, delim byte) (val float64, ok bool, err error) {
+	val, err = parseNumber(field, delim)
+	return ms.check(field, val, err)
+}
+
+// check сверяет разобранное значение поля с обозначениями пропусков;
+// err - ошибка разбора числа. ok == false означает пропуск
+func (ms missingSet) check(field string, val float64, err error) (float64, bool, error) {
+	if err == nil {
+		if math.IsNaN(val) {
+			return val, false, nil
+		}
+		for _, m := range ms.numbers {
+			if val == m {
+				return val, false, nil
+			}
+		}
+		return val, true, nil
+	}
+	for _, m := range ms.text {
+		if strings.EqualFold(field, m) {
+			return math.NaN(), false, nil
+		}
+	}
+	return 0, false, err
+}
+
+// ReadTableFromFile читает таблицу из текстового файла и возвращает *models.Table.
+// Разделитель (табуляция, точка с запятой, запятая или пробелы) определяется
+// автоматически, метки могут быть как в двойных кавычках, так и без них.
+// Пропущенные значения (DefaultMissingValues) отмечаются в маске таблицы.
+// Файлы, сжатые gzip, распаковываются автоматически
 func ReadTableFromFile(filename string) (*models.Table, error) {
-	file, err := os.Open(filename)
+	return ReadTableFromFileWithOptions(filename, Options{})
+}
+
+// ReadTableFromFileWithOptions читает таблицу с заданными параметрами.
+// Формат файла определяется по реестру (см. Open)
+func ReadTableFromFileWithOptions(filename string, opts Options) (*models.Table, error) {
+	return Open(filename, opts)
+}
+
+// ReadTable читает текстовую таблицу из r за один проход. Данные, сжатые gzip,
+// распознаются по сигнатуре и распаковываются на лету. Для потоков других
+// форматов используется Decode
+func ReadTable(r io.Reader, opts Options) (*models.Table, error) {
+	r, err := decompress(bufio.NewReaderSize(r, readBufferSize))
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return readText(r, opts, 0)
+}
+
+// readBufferSize - размер буфера чтения
+const readBufferSize = 1 << 16
+
+// isGzip проверяет сигнатуру gzip в начале потока
+func isGzip(br *bufio.Reader) bool {
+	magic, err := br.Peek(2)
+	return err == nil && magic[0] == 0x1f && magic[1] == 0x8b
+}
+
+// decompress возвращает распаковывающий поток для данных gzip, иначе сам br
+func decompress(br *bufio.Reader) (io.Reader, error) {
+	if !isGzip(br) {
+		return br, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, errors.New("ошибка чтения gzip: " + err.Error())
+	}
+	return zr, nil
+}
+
+// gzipSize возвращает размер распакованных данных из последних 4 байт файла gzip
+// (ISIZE, размер по модулю 2^32). При ошибке возвращается оценка по сжатому размеру
+func gzipSize(file *os.File, compressed int64) int64 {
+	var tail [4]byte
+	if compressed < 18 {
+		return 0
+	}
+	if _, err := file.ReadAt(tail[:], compressed-4); err != nil {
+		return 0
+	}
+	size := int64(binary.LittleEndian.Uint32(tail[:]))
+	// ISIZE переполняется для файлов больше 4 ГиБ; текст сжимается не более
+	// чем в ~20 раз, так что это лишь оценка
+	return max(size, compressed)
+}
 
-	scanner := bufio.NewScanner(file)
-	var lines []string
+// readText разбирает текстовую таблицу за один проход. sizeHint - размер
+// данных в байтах (0 - неизвестен) для предварительного выделения Data
+func readText(r io.Reader, opts Options, sizeHint int64) (*models.Table, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, readBufferSize), maxLineLength)
 
-	// Читаем все строки, игнорируя комментарии и пустые строки
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	lineNo := 0 // номер непустой строки без комментария, как в сообщениях об ошибках
+	next := func() (string, bool) {
+		for scanner.Scan() {
+			// Табуляции по краям не отрезаются: ведущая - пустая метка в углу
+			// заголовка, завершающая - пустое последнее поле строки TSV
+			line := strings.Trim(scanner.Text(), " \r")
+			if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			lineNo++
+			return line, true
 		}
-		lines = append(lines, line)
+		return "", false
 	}
 
-	if len(lines) < 2 {
+	header, ok1 := next()
+	first, ok2 := next()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !ok1 || !ok2 {
 		return nil, errors.New("недостаточно данных в файле: нужна хотя бы одна строка заголовков и одна строка данных")
 	}
 
+	delim := detectDelimiter(header, first)
+
 	// Парсим заголовки столбцов (первая строка)
-	columnLabels, err := parseQuotedStrings(lines[0])
+	columnLabels, err := splitFields(header, delim)
 	if err != nil {
-		return nil, err
+		return nil, errors.New("строка 1: " + err.Error())
 	}
-	cols := len(columnLabels)
 
-	// Парсим строки данных
-	var rowLabels []string
-	var data []float64
+	// Если в заголовке столько же полей, сколько в строке данных,
+	// первое поле - подпись столбца меток строк, и оно отбрасывается
+	firstRow, err := splitFields(first, delim)
+	if err != nil {
+		return nil, errors.New("строка 2: " + err.Error())
+	}
+	if len(columnLabels) == len(firstRow) {
+		columnLabels = columnLabels[1:]
+	}
+	cols := len(columnLabels)
+	if cols == 0 {
+		return nil, errors.New("в заголовке нет меток столбцов")
+	}
 
-	for i, line := range lines[1:] {
-		fields := strings.Fields(line)
-		if len(fields) != cols+1 {
-			return nil, errors.New(
-				"неверное количество полей в строке " + strconv.Itoa(i+2) +
-					": ожидается " + strconv.Itoa(cols+1) + ", получено " + strconv.Itoa(len(fields)))
-		}
+	// Оценка числа строк по длине первой строки данных; каждое значение
+	// занимает не меньше двух байт, что ограничивает выделение сверху
+	estRows := 16
+	if sizeHint > 0 {
+		estRows = int(sizeHint/int64(len(first)+1)) + 1
+		estRows += estRows / 8
+		estRows = min(estRows, int(sizeHint/int64(2*cols))+1)
+	}
+	rowLabels := make([]string, 0, estRows)
+	data := make([]float64, 0, estRows*cols)
+	var missing []int // индексы пропущенных значений в data
+	ms := opts.missingSet()
 
-		// Первая часть — метка строки (в кавычках)
-		rowLabel, err := unquote(fields[0])
+	// Парсим строки данных
+	for line, ok := first, true; ok; line, ok = next() {
+		row := strconv.Itoa(lineNo)
+		label, i, _, err := nextField(line, 0, delim)
 		if err != nil {
-			return nil, err
+			return nil, errors.New("строка " + row + ": " + err.Error())
 		}
-		rowLabels = append(rowLabels, rowLabel)
+		// Первая часть — метка строки
+		rowLabels = append(rowLabels, label)
 
 		// Остальные — числовые значения
-		for j, field := range fields[1:] {
-			val, err := strconv.ParseFloat(field, 64)
+		n := 1
+		for ; i >= 0; n++ {
+			var field string
+			var hasField bool
+			field, i, hasField, err = nextField(line, i, delim)
+			if err != nil {
+				return nil, errors.New("строка " + row + ": " + err.Error())
+			}
+			if !hasField {
+				break
+			}
+			if n > cols {
+				continue // лишние поля только считаются для сообщения об ошибке
+			}
+			val, valid, err := ms.parse(field, delim)
 			if err != nil {
 				return nil, errors.New(
-					"ошибка парсинга числа в строке " + strconv.Itoa(i+2) +
-						", столбец " + strconv.Itoa(j+2) + ": " + err.Error())
+					"ошибка парсинга числа в строке " + row +
+						", столбец " + strconv.Itoa(n+1) + ": " + err.Error())
+			}
+			if !valid {
+				missing = append(missing, len(data))
 			}
 			data = append(data, val)
 		}
+		if n != cols+1 {
+			return nil, errors.New(
+				"неверное количество полей в строке " + row +
+					": ожидается " + strconv.Itoa(cols+1) + ", получено " + strconv.Itoa(n))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
 	}
 
 	rows := len(rowLabels)
 
 	// Создаём и возвращаем Table
 	table := models.NewTable(rows, cols, data, columnLabels, rowLabels)
+	for _, k := range missing {
+		table.SetMissing(k/cols, k%cols)
+	}
 	return table, nil
 }
 
-// parseQuotedStrings разбирает строку с quoted-строками (в "кавычках")
-func parseQuotedStrings(line string) ([]string, error) {
-	var result []string
-	fields := strings.Fields(line)
-	for _, field := range fields {
-		unquoted, err := unquote(field)
+// maxLineLength - максимальная длина строки таблицы
+const maxLineLength = 1 << 30
+
+// detectDelimiter определяет разделитель по строке заголовка и первой строке данных.
+// Учитываются только символы вне кавычек; приоритет: табуляция, точка с запятой,
+// запятая, иначе - пробелы
+func detectDelimiter(header, row string) byte {
+	for _, d := range []byte{'\t', ';', ','} {
+		if countUnquoted(header, d) > 0 && countUnquoted(row, d) > 0 {
+			return d
+		}
+	}
+	// Заголовок из одного столбца может не содержать разделителя
+	for _, d := range []byte{'\t', ';', ','} {
+		if countUnquoted(row, d) > 0 {
+			return d
+		}
+	}
+	return whitespace
+}
+
+// countUnquoted считает вхождения символа c вне двойных кавычек
+func countUnquoted(line string, c byte) int {
+	n := 0
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case c:
+			if !quoted {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// splitFields разбивает строку на поля по разделителю delim (или по пробелам
+// при delim == whitespace). Поля в двойных кавычках могут содержать разделитель
+// и пробелы, кавычки снимаются, "" внутри кавычек означает одну кавычку
+func splitFields(line string, delim byte) ([]string, error) {
+	var fields []string
+	for i := 0; i >= 0; {
+		field, next, ok, err := nextField(line, i, delim)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, unquoted)
+		if ok {
+			fields = append(fields, field)
+		}
+		i = next
+	}
+	return fields, nil
+}
+
+// nextField читает поле, начинающееся с позиции i, и возвращает позицию
+// следующего поля (-1, если строка закончилась). ok == false, если полей
+// больше нет (только при разделении пробелами). Поля без кавычек
+// возвращаются как подстроки line, без выделения памяти
+func nextField(line string, i int, delim byte) (field string, next int, ok bool, err error) {
+	if delim == whitespace {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return "", -1, false, nil
+		}
+	} else {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+	}
+
+	if i < len(line) && line[i] == '"' {
+		var sb strings.Builder
+		i++
+		closed := false
+		for i < len(line) {
+			if line[i] == '"' {
+				if i+1 < len(line) && line[i+1] == '"' {
+					sb.WriteByte('"')
+					i += 2
+					continue
+				}
+				i++
+				closed = true
+				break
+			}
+			sb.WriteByte(line[i])
+			i++
+		}
+		if !closed {
+			return "", -1, false, errors.New("незакрытая кавычка: " + line)
+		}
+		field = sb.String()
+		for i < len(line) && line[i] == ' ' && delim != whitespace {
+			i++
+		}
+		if i < len(line) && !isFieldEnd(line[i], delim) {
+			return "", -1, false, errors.New("лишние символы после кавычек: " + line)
+		}
+	} else {
+		start := i
+		for i < len(line) && !isFieldEnd(line[i], delim) {
+			i++
+		}
+		field = strings.TrimSpace(line[start:i])
+	}
+
+	if i >= len(line) {
+		return field, -1, true, nil
+	}
+	if delim == whitespace {
+		return field, i, true, nil
 	}
-	return result, nil
+	// Пропускаем разделитель; разделитель в конце строки означает
+	// пустое последнее поле, которое вернет следующий вызов
+	return field, i + 1, true, nil
 }
 
-// unquote убирает кавычки с начала и конца строки, если они есть
-func unquote(s string) (string, error) {
-	if len(s) < 2 {
-		return "", errors.New("строка слишком короткая для кавычек: " + s)
+// isFieldEnd проверяет, заканчивает ли символ c поле
+func isFieldEnd(c, delim byte) bool {
+	if delim == whitespace {
+		return isSpace(c)
 	}
-	if s[0] != '"' || s[len(s)-1] != '"' {
-		return "", errors.New("строка не заключена в кавычки: " + s)
+	return c == delim
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// parseNumber разбирает число; при разделителе ';' допускается десятичная запятая
+func parseNumber(field string, delim byte) (float64, error) {
+	if delim == ';' {
+		field = strings.Replace(field, ",", ".", 1)
 	}
-	return s[1 : len(s)-1], nil
+	return strconv.ParseFloat(field, 64)
 }
@@ -0,0 +1,259 @@
+package reader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadTableFromFileFormats(t *testing.T) {
+	wantCols := []string{"A", "B", "C"}
+	wantRows := []string{"1005", "1012.5"}
+	wantData := []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6}
+
+	tests := []struct {
+		name    string
+		content string
+		cols    []string
+		rows    []string
+	}{
+		{
+			name:    "исходный формат с кавычками",
+			content: "\t\"A\"\t\"B\"\t\"C\"\n\"1005\"\t0.1\t0.2\t0.3\n\"1012.5\"\t0.4\t0.5\t0.6\n",
+		},
+		{
+			name:    "пробелы без кавычек",
+			content: "A B C\n1005 0.1 0.2 0.3\n1012.5   0.4 0.5 0.6\n",
+		},
+		{
+			name:    "CSV с подписью столбца меток",
+			content: "altitude,A,B,C\n1005,0.1,0.2,0.3\n1012.5,0.4,0.5,0.6\n",
+		},
+		{
+			name:    "CSV с пустым углом",
+			content: ",\"A\",\"B\",\"C\"\n\"1005\",0.1,0.2,0.3\n\"1012.5\",0.4,0.5,0.6\n",
+		},
+		{
+			name:    "точка с запятой и десятичная запятая",
+			content: ";A;B;C\n1005;0,1;0,2;0,3\n1012,5;0,4;0,5;0,6\n",
+			rows:    []string{"1005", "1012,5"},
+		},
+		{
+			name:    "TSV без кавычек",
+			content: "A\tB\tC\n1005\t0.1\t0.2\t0.3\n1012.5\t0.4\t0.5\t0.6\n",
+		},
+		{
+			name:    "метки с пробелами в кавычках",
+			content: "\"col A\" \"col B\" \"col C\"\n\"12:00 UTC\" 0.1 0.2 0.3\n\"12:30 UTC\" 0.4 0.5 0.6\n",
+			cols:    []string{"col A", "col B", "col C"},
+			rows:    []string{"12:00 UTC", "12:30 UTC"},
+		},
+		{
+			name:    "CSV с запятой внутри кавычек",
+			content: "\"\",\"A, m\",\"B\",\"C\"\n\"1005\",0.1,0.2,0.3\n\"1012.5\",0.4,0.5,0.6\n",
+			cols:    []string{"A, m", "B", "C"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "table.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			table, err := ReadTableFromFile(path)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+
+			cols, rows := wantCols, wantRows
+			if tt.cols != nil {
+				cols = tt.cols
+			}
+			if tt.rows != nil {
+				rows = tt.rows
+			}
+			if !reflect.DeepEqual(table.ColumnLabels, cols) {
+				t.Errorf("метки столбцов: получено %q, ожидалось %q", table.ColumnLabels, cols)
+			}
+			if !reflect.DeepEqual(table.RowLabels, rows) {
+				t.Errorf("метки строк: получено %q, ожидалось %q", table.RowLabels, rows)
+			}
+			if !reflect.DeepEqual(table.Data, wantData) {
+				t.Errorf("данные: получено %v, ожидалось %v", table.Data, wantData)
+			}
+		})
+	}
+}
+
+func TestReadTableFromFileErrors(t *testing.T) {
+	tests := map[string]string{
+		"разное число полей": "A B\n1 0.1 0.2\n2 0.3\n",
+		"не число":           "A B\n1 0.1 x\n",
+		"незакрытая кавычка": "\"A \"B\"\n\"1 0.1 0.2\n",
+		"одна строка":        "A B\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "table.txt")
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadTableFromFile(path); err == nil {
+				t.Error("ожидалась ошибка")
+			}
+		})
+	}
+}
+
+func TestReadTableFromFileMissing(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    Options
+		valid   []bool
+	}{
+		{
+			name:    "стандартные обозначения",
+			content: "A,B,C\n1,0.1,NA,0.3\n2,,-9999.0,NaN\n",
+			valid:   []bool{true, false, true, false, false, false},
+		},
+		{
+			name:    "TSV с пустой последней ячейкой",
+			content: "\tA\tB\tC\r\n1005\t0.1\t\t0.3\r\n1012.5\t0.4\t0.5\t\r\n",
+			valid:   []bool{true, false, true, true, true, false},
+		},
+		{
+			name:    "TSV без угла с пустой последней ячейкой",
+			content: "A\tB\tC\n1005\t0.1\t0.2\t\n1012.5\t\t0.5\t0.6\n",
+			valid:   []bool{true, true, false, false, true, true},
+		},
+		{
+			name:    "пользовательское обозначение",
+			content: "A B C\n1 0.1 -1 0.3\n2 0.4 0.5 -9999\n",
+			opts:    Options{MissingValues: []string{"-1"}},
+			valid:   []bool{true, false, true, true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "table.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			table, err := ReadTableFromFileWithOptions(path, tt.opts)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			if table.Rows*table.Columns != len(tt.valid) {
+				t.Fatalf("размер %dx%d, ожидалось %d ячеек", table.Rows, table.Columns, len(tt.valid))
+			}
+			for k, want := range tt.valid {
+				if got := table.Valid(k/table.Columns, k%table.Columns); got != want {
+					t.Errorf("ячейка %d: валидность %v, ожидалось %v", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadTableGzipAndStream(t *testing.T) {
+	content := "    \"A\"\t\"B\"\n\"1005\"\t0.1\t0.2\n\"1012.5\"\tNA\t0.4\n"
+	want, err := ReadTable(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Rows != 2 || want.Columns != 2 || want.Valid(1, 0) {
+		t.Fatalf("неверная таблица: %+v", want)
+	}
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(content))
+	zw.Close()
+
+	// Сжатый поток распознается по сигнатуре, а не по расширению
+	for _, name := range []string{"table.txt.gz", "table.txt"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, gz.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadTableFromFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got.RowLabels, want.RowLabels) || !reflect.DeepEqual(got.Mask, want.Mask) ||
+			got.Get(1, 1) != want.Get(1, 1) {
+			t.Errorf("%s: таблица отличается от несжатой", name)
+		}
+	}
+	got, err := ReadTable(bytes.NewReader(gz.Bytes()), Options{})
+	if err != nil || !reflect.DeepEqual(got.RowLabels, want.RowLabels) {
+		t.Errorf("ReadTable для gzip: %v", err)
+	}
+}
+
+// writeBenchTable записывает таблицу rows x cols в формате входных файлов
+func writeBenchTable(b *testing.B, rows, cols int, compress bool) (string, int64) {
+	b.Helper()
+	rng := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for j := range cols {
+		fmt.Fprintf(&sb, "\t\"C%d\"", j)
+	}
+	sb.WriteString("\n")
+	for i := range rows {
+		fmt.Fprintf(&sb, "\"%g\"", 1000+7.5*float64(i))
+		for range cols {
+			fmt.Fprintf(&sb, "\t%.5f", rng.Float64())
+		}
+		sb.WriteString("\n")
+	}
+	data := []byte(sb.String())
+	if compress {
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		zw.Write(data)
+		zw.Close()
+		data = gz.Bytes()
+	}
+	path := filepath.Join(b.TempDir(), "table.txt")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		b.Fatal(err)
+	}
+	return path, int64(sb.Len())
+}
+
+func BenchmarkReadTableFromFile(b *testing.B) {
+	for _, bc := range []struct {
+		rows, cols int
+		gzip       bool
+	}{
+		{1000, 2000, false},
+		{1000, 2000, true},
+		{2000, 5000, false},
+	} {
+		name := fmt.Sprintf("%dx%d", bc.rows, bc.cols)
+		if bc.gzip {
+			name += ".gz"
+		}
+		b.Run(name, func(b *testing.B) {
+			path, size := writeBenchTable(b, bc.rows, bc.cols, bc.gzip)
+			b.SetBytes(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := ReadTableFromFile(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package reader
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"classification-project/internal/models"
+)
+
+// FormatXLSX - книга Excel (Office Open XML), лист Options.Sheet
+const FormatXLSX = "xlsx"
+
+func init() {
+	Register(Format{
+		Name:       FormatXLSX,
+		Extensions: []string{".xlsx"},
+		Sniff:      isXLSX,
+		Reader: TableReaderFunc(func(in *Input, opts Options) (*models.Table, error) {
+			at, err := in.ReaderAt()
+			if err != nil {
+				return nil, err
+			}
+			return ReadXLSXTable(at, in.Size, opts)
+		}),
+	})
+}
+
+// isXLSX распознает архив Office Open XML: zip, первый элемент которого -
+// [Content_Types].xml (так пишут Excel, LibreOffice и большинство библиотек)
+func isXLSX(head []byte) bool {
+	const contentTypes = "[Content_Types].xml"
+	return len(head) >= 30+len(contentTypes) &&
+		bytes.HasPrefix(head, []byte("PK\x03\x04")) &&
+		string(head[30:30+len(contentTypes)]) == contentTypes
+}
+
+// ReadXLSXTable читает лист opts.Sheet ("" - первый лист книги) как таблицу:
+// первая непустая строка листа - метки столбцов, первый столбец - метки строк.
+// Пустые ячейки и ячейки с ошибками (#N/A) всегда отмечаются пропусками,
+// как и обозначения пропусков из opts.MissingValues
+func ReadXLSXTable(r io.ReaderAt, size int64, opts Options) (*models.Table, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.New("ошибка чтения xlsx: " + err.Error())
+	}
+	sheetPath, err := xlsxSheetPath(zr, opts.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := xlsxSharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+	f, err := zr.Open(sheetPath)
+	if err != nil {
+		return nil, errors.New("xlsx: нет листа " + sheetPath)
+	}
+	defer f.Close()
+	grid, err := xlsxCells(f, shared)
+	if err != nil {
+		return nil, errors.New("xlsx, лист " + sheetPath + ": " + err.Error())
+	}
+	return gridTable(grid, opts)
+}
+
+// xlsxCell - значение ячейки; bad - ячейка с ошибкой формулы
+type xlsxCell struct {
+	text string
+	bad  bool
+}
+
+// xlsxRow - строка листа, в которой есть ячейки: номер от 0 и ячейки по
+// номерам столбцов. Хранятся только существующие ячейки, поэтому ссылка
+// вида XFD1048576 не раздувает память
+type xlsxRow struct {
+	index int
+	cells map[int]xlsxCell
+}
+
+// Размеры листа Excel
+const (
+	xlsxMaxRows = 1 << 20
+	xlsxMaxCols = 1 << 14
+)
+
+// gridTable строит таблицу из строк листа. Полностью пустые строки пропускаются,
+// первая непустая строка - заголовок, число столбцов задается его последней
+// непустой меткой
+func gridTable(rows []xlsxRow, opts Options) (*models.Table, error) {
+	for len(rows) > 0 && rows[0].empty() {
+		rows = rows[1:]
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("недостаточно данных на листе: нужна строка заголовков и хотя бы одна строка данных")
+	}
+	headerIndex := rows[0].index
+	header, rows := rows[0].cells, rows[1:]
+	cols := 0
+	for j, c := range header {
+		if j > cols && c.text != "" {
+			cols = j
+		}
+	}
+	if cols <= 0 {
+		return nil, errors.New("в строке заголовков (строка " + strconv.Itoa(headerIndex+1) +
+			" листа) нет меток столбцов")
+	}
+	columnLabels := make([]string, cols)
+	for j := range columnLabels {
+		columnLabels[j] = header[j+1].text
+	}
+
+	ms := opts.missingSet()
+	var rowLabels []string
+	var data []float64
+	var missing []int
+	for _, row := range rows {
+		if row.empty() {
+			continue
+		}
+		rowLabels = append(rowLabels, row.cells[0].text)
+		for j := 1; j <= cols; j++ {
+			c := row.cells[j]
+			val, err := parseXLSXNumber(c.text)
+			val, ok, err := ms.check(c.text, val, err)
+			// Пустая ячейка - пропуск независимо от списка обозначений
+			if c.bad || c.text == "" {
+				val, ok, err = 0, false, nil
+			}
+			if err != nil {
+				return nil, errors.New("ошибка парсинга числа в строке " + strconv.Itoa(row.index+1) +
+					", столбец " + strconv.Itoa(j+1) + ": " + err.Error())
+			}
+			if !ok {
+				missing = append(missing, len(data))
+			}
+			data = append(data, val)
+		}
+	}
+	if len(rowLabels) == 0 {
+		return nil, errors.New("на листе нет строк данных")
+	}
+
+	table := models.NewTable(len(rowLabels), cols, data, columnLabels, rowLabels)
+	for _, k := range missing {
+		table.SetMissing(k/cols, k%cols)
+	}
+	return table, nil
+}
+
+// empty сообщает, что в строке нет ни значений, ни ячеек с ошибками
+func (r xlsxRow) empty() bool {
+	for _, c := range r.cells {
+		if c.text != "" || c.bad {
+			return false
+		}
+	}
+	return true
+}
+
+// parseXLSXNumber разбирает число из ячейки. Excel хранит числа в <v> без
+// учета локали (точка и показатель e), поэтому запятая не считается десятичной
+func parseXLSXNumber(text string) (float64, error) {
+	return strconv.ParseFloat(text, 64)
+}
+
+// xlsxSheetPath находит файл листа по имени через workbook.xml и связи книги
+func xlsxSheetPath(zr *zip.Reader, name string) (string, error) {
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			// Атрибут r:id; пространство имен не проверяется
+			ID string `xml:"id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xlsxUnmarshal(zr, "xl/workbook.xml", &wb); err != nil {
+		return "", err
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xlsxUnmarshal(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", errors.New("xlsx: в книге нет листов")
+	}
+
+	names := make([]string, len(wb.Sheets))
+	for i, s := range wb.Sheets {
+		names[i] = s.Name
+		if name != "" && s.Name != name {
+			continue
+		}
+		for _, rel := range rels.Relationships {
+			if rel.ID != s.ID {
+				continue
+			}
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+		return "", errors.New("xlsx: не найден файл листа " + strconv.Quote(s.Name))
+	}
+	return "", errors.New("xlsx: нет листа " + strconv.Quote(name) + ", доступны: " + strings.Join(names, ", "))
+}
+
+func xlsxUnmarshal(zr *zip.Reader, name string, v any) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return errors.New("xlsx: нет " + name)
+	}
+	defer f.Close()
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return errors.New("xlsx, " + name + ": " + err.Error())
+	}
+	return nil
+}
+
+// xlsxSharedStrings читает таблицу общих строк (может отсутствовать).
+// Текст строки с форматированием собирается из всех фрагментов <t>,
+// кроме фонетических подсказок <rPh>
+func xlsxSharedStrings(zr *zip.Reader) ([]string, error) {
+	f, err := zr.Open("xl/sharedStrings.xml")
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	var strs []string
+	var sb strings.Builder
+	inT, inPh := false, false
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, errors.New("xlsx, sharedStrings.xml: " + err.Error())
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				sb.Reset()
+			case "t":
+				inT = true
+			case "rPh":
+				inPh = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, sb.String())
+			case "t":
+				inT = false
+			case "rPh":
+				inPh = false
+			}
+		case xml.CharData:
+			if inT && !inPh {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+// xlsxCells читает ячейки листа потоково и возвращает непустые строки
+// в порядке номеров
+func xlsxCells(r io.Reader, shared []string) ([]xlsxRow, error) {
+	cells := make(map[int]map[int]xlsxCell)
+	var cell xlsxCell
+	var ref, typ string
+	var value strings.Builder
+	inValue := false
+	row, col := -1, -1
+
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row++
+				col = -1
+				if n, err := strconv.Atoi(attr(t, "r")); err == nil && n > 0 {
+					row = n - 1
+				}
+			case "c":
+				ref, typ = attr(t, "r"), attr(t, "t")
+				col++
+				if ref != "" {
+					r, c, err := parseCellRef(ref)
+					if err != nil {
+						return nil, err
+					}
+					row, col = r, c
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				cell = xlsxCell{text: strings.TrimSpace(value.String())}
+				switch typ {
+				case "s":
+					k, err := strconv.Atoi(cell.text)
+					if err != nil || k < 0 || k >= len(shared) {
+						return nil, errors.New("ячейка " + ref + ": неверный индекс общей строки")
+					}
+					cell.text = strings.TrimSpace(shared[k])
+				case "b":
+					// логическое значение остается числом 0 или 1
+				case "e":
+					cell = xlsxCell{bad: true}
+				}
+				row = max(row, 0)
+				if row >= xlsxMaxRows || col >= xlsxMaxCols {
+					return nil, errors.New("ячейка за пределами листа: строка " + strconv.Itoa(row+1) +
+						", столбец " + strconv.Itoa(col+1))
+				}
+				if cells[row] == nil {
+					cells[row] = make(map[int]xlsxCell)
+				}
+				cells[row][col] = cell
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+
+	rows := make([]xlsxRow, 0, len(cells))
+	for i, c := range cells {
+		rows = append(rows, xlsxRow{index: i, cells: c})
+	}
+	sort.Slice(rows, func(a, b int) bool { return rows[a].index < rows[b].index })
+	return rows, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// parseCellRef переводит ссылку вида "AB12" в номера строки и столбца от 0
+func parseCellRef(ref string) (row, col int, err error) {
+	i := 0
+	for i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z' {
+		col = col*26 + int(ref[i]-'A'+1)
+		i++
+	}
+	n, err := strconv.Atoi(ref[i:])
+	if i == 0 || err != nil || n <= 0 || n > xlsxMaxRows || col > xlsxMaxCols {
+		return 0, 0, errors.New("неверная ссылка на ячейку " + strconv.Quote(ref))
+	}
+	return n - 1, col - 1, nil
+}
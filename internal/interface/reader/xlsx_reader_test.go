@@ -0,0 +1,265 @@
+package reader
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type xlsxTestSheet struct {
+	name  string
+	cells [][]string // "" - ячейка отсутствует, "#N/A" - ошибка
+}
+
+// buildXLSX собирает минимальную книгу xlsx: числа пишутся как числовые ячейки,
+// остальной текст - через таблицу общих строк
+func buildXLSX(t *testing.T, sheets []xlsxTestSheet) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, content string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+
+	write("[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`)
+	var wb, rels strings.Builder
+	wb.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	var shared []string
+	for i, s := range sheets {
+		fmt.Fprintf(&wb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, s.name, i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+
+		var sheet strings.Builder
+		sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+		for r, row := range s.cells {
+			fmt.Fprintf(&sheet, `<row r="%d">`, r+1)
+			for c, v := range row {
+				ref := string(rune('A'+c)) + strconv.Itoa(r+1)
+				switch _, err := strconv.ParseFloat(v, 64); {
+				case v == "":
+				case v == "#N/A":
+					fmt.Fprintf(&sheet, `<c r="%s" t="e"><v>#N/A</v></c>`, ref)
+				case err == nil:
+					fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, v)
+				default:
+					fmt.Fprintf(&sheet, `<c r="%s" t="s"><v>%d</v></c>`, ref, len(shared))
+					shared = append(shared, v)
+				}
+			}
+			sheet.WriteString(`</row>`)
+		}
+		sheet.WriteString(`</sheetData></worksheet>`)
+		write(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.String())
+	}
+	wb.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+	write("xl/workbook.xml", wb.String())
+	write("xl/_rels/workbook.xml.rels", rels.String())
+
+	var sst strings.Builder
+	sst.WriteString(`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	for _, s := range shared {
+		// Форматированная строка из двух фрагментов проверяет сборку текста
+		if len(s) > 1 {
+			fmt.Fprintf(&sst, `<si><r><t>%s</t></r><r><t>%s</t></r></si>`, s[:1], s[1:])
+		} else {
+			fmt.Fprintf(&sst, `<si><t>%s</t></si>`, s)
+		}
+	}
+	sst.WriteString(`</sst>`)
+	write("xl/sharedStrings.xml", sst.String())
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSXTable(t *testing.T) {
+	book := buildXLSX(t, []xlsxTestSheet{
+		{name: "d", cells: [][]string{
+			{"", "A", "B"},
+			{"1005", "0.1", "0.2"},
+			{"1012.5", "0.3", "0.4"},
+		}},
+		{name: "Vol", cells: [][]string{
+			{"altitude", "A", "B", "C"},
+			{"1005", "1", "", "3"},
+			{},
+			{"1012.5", "NA", "#N/A", "6"},
+		}},
+	})
+	// Расширение не важно: книга распознается по содержимому
+	path := filepath.Join(t.TempDir(), "scene.dat")
+	if err := os.WriteFile(path, book, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.ColumnLabels, []string{"A", "B"}) || !reflect.DeepEqual(d.RowLabels, []string{"1005", "1012.5"}) {
+		t.Errorf("метки первого листа: %q x %q", d.RowLabels, d.ColumnLabels)
+	}
+	if !reflect.DeepEqual(d.Data, []float64{0.1, 0.2, 0.3, 0.4}) {
+		t.Errorf("данные первого листа: %v", d.Data)
+	}
+
+	vol, err := Open(path, Options{Sheet: "Vol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vol.Rows != 2 || vol.Columns != 3 {
+		t.Fatalf("размер листа Vol %dx%d, ожидалось 2x3", vol.Rows, vol.Columns)
+	}
+	valid := []bool{true, false, true, false, false, true}
+	for k, want := range valid {
+		if got := vol.Valid(k/3, k%3); got != want {
+			t.Errorf("ячейка %d: валидность %v, ожидалось %v", k, got, want)
+		}
+	}
+	if vol.Get(1, 2) != 6 {
+		t.Errorf("Vol(1,2) = %v", vol.Get(1, 2))
+	}
+
+	if _, err := Open(path, Options{Sheet: "beta"}); err == nil || !strings.Contains(err.Error(), "Vol") {
+		t.Errorf("ожидалась ошибка со списком листов, получено %v", err)
+	}
+}
+
+func TestParseCellRef(t *testing.T) {
+	for ref, want := range map[string][2]int{"A1": {0, 0}, "Z3": {2, 25}, "AA10": {9, 26}, "XFD1048576": {1048575, 16383}} {
+		r, c, err := parseCellRef(ref)
+		if err != nil || r != want[0] || c != want[1] {
+			t.Errorf("%s: (%d, %d, %v), ожидалось %v", ref, r, c, err, want)
+		}
+	}
+	for _, ref := range []string{"", "A", "1", "a1", "A0"} {
+		if _, _, err := parseCellRef(ref); err == nil {
+			t.Errorf("%q: ожидалась ошибка", ref)
+		}
+	}
+}
+
+// TestXLSXNumbers проверяет, что числа ячеек разбираются без учета локали
+func TestXLSXNumbers(t *testing.T) {
+	sheet := `<worksheet><sheetData>
+		<row r="1"><c r="B1" t="inlineStr"><is><t>A</t></is></c><c r="C1" t="inlineStr"><is><t>B</t></is></c></row>
+		<row r="2"><c r="A2"><v>1005</v></c><c r="B2"><v>2.5E-3</v></c><c r="C2" t="inlineStr"><is><t> -7 </t></is></c></row>
+	</sheetData></worksheet>`
+	rows, err := xlsxCells(strings.NewReader(sheet), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := gridTable(rows, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.Data, []float64{2.5e-3, -7}) {
+		t.Errorf("данные %v", table.Data)
+	}
+
+	// Запятая в тексте ячейки - не десятичный разделитель
+	comma := strings.Replace(sheet, "<t> -7 </t>", "<t>1,5</t>", 1)
+	if rows, err = xlsxCells(strings.NewReader(comma), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gridTable(rows, Options{}); err == nil {
+		t.Error("ожидалась ошибка для числа с запятой")
+	}
+}
+
+// TestXLSXSparseCells проверяет, что далекая ссылка на ячейку не создает
+// плотную сетку до этой ячейки, а ссылка за пределами листа - ошибка
+func TestXLSXSparseCells(t *testing.T) {
+	sheet := `<worksheet><sheetData>
+		<row r="1"><c r="B1" t="inlineStr"><is><t>A</t></is></c></row>
+		<row r="2"><c r="A2"><v>1</v></c><c r="B2"><v>0.5</v></c></row>
+		<row r="1048576"><c r="XFD1048576"><v>9</v></c></row>
+	</sheetData></worksheet>`
+	rows, err := xlsxCells(strings.NewReader(sheet), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := 0
+	for _, row := range rows {
+		cells += len(row.cells)
+	}
+	if len(rows) != 3 || cells != 4 {
+		t.Fatalf("строк %d, ячеек %d, ожидалось 3 и 4", len(rows), cells)
+	}
+	table, err := gridTable(rows, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Строка только с ячейкой за последним столбцом таблицы дает пропуск
+	if table.Rows != 2 || table.Columns != 1 || table.Get(0, 0) != 0.5 || table.Valid(1, 0) {
+		t.Errorf("таблица %dx%d, данные %v", table.Rows, table.Columns, table.Data)
+	}
+
+	for _, ref := range []string{"XFE1", "A1048577"} {
+		bad := `<worksheet><sheetData><row><c r="` + ref + `"><v>1</v></c></row></sheetData></worksheet>`
+		if _, err := xlsxCells(strings.NewReader(bad), nil); err == nil {
+			t.Errorf("%s: ожидалась ошибка для ячейки за пределами листа", ref)
+		}
+	}
+	// Без ссылок номер столбца растет с каждой ячейкой
+	var sb strings.Builder
+	sb.WriteString(`<worksheet><sheetData><row>`)
+	for range xlsxMaxCols + 1 {
+		sb.WriteString(`<c><v>1</v></c>`)
+	}
+	sb.WriteString(`</row></sheetData></worksheet>`)
+	if _, err := xlsxCells(strings.NewReader(sb.String()), nil); err == nil {
+		t.Error("ожидалась ошибка для строки длиннее листа")
+	}
+}
+
+func TestXLSXHeaderRow(t *testing.T) {
+	// Пустая первая строка, метки столбцов во второй, пустая ячейка и
+	// пользовательское обозначение пропуска в данных
+	sheet := `<worksheet><sheetData>
+		<row r="1"><c r="A1" t="inlineStr"><is><t></t></is></c></row>
+		<row r="2"><c r="B2" t="inlineStr"><is><t>A</t></is></c><c r="C2" t="inlineStr"><is><t>B</t></is></c></row>
+		<row r="3"><c r="A3"><v>1</v></c><c r="B3"><v>-1</v></c><c r="C3"><v>2</v></c></row>
+		<row r="5"><c r="A5"><v>2</v></c><c r="B5"><v>3</v></c><c r="C5"/></row>
+	</sheetData></worksheet>`
+	rows, err := xlsxCells(strings.NewReader(sheet), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := gridTable(rows, Options{MissingValues: []string{"-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Rows != 2 || table.Columns != 2 || table.ColumnLabels[1] != "B" || table.RowLabels[1] != "2" {
+		t.Fatalf("таблица %dx%d, метки %v %v", table.Rows, table.Columns, table.ColumnLabels, table.RowLabels)
+	}
+	if table.Valid(0, 0) || table.Get(0, 1) != 2 || table.Get(1, 0) != 3 || table.Valid(1, 1) {
+		t.Errorf("данные %v", table.Data)
+	}
+
+	// Номер строки заголовков в ошибке соответствует листу
+	sheet = `<worksheet><sheetData>
+		<row r="3"><c r="A3" t="inlineStr"><is><t>x</t></is></c></row>
+		<row r="4"><c r="A4"><v>1</v></c><c r="B4"><v>1</v></c></row>
+	</sheetData></worksheet>`
+	if rows, err = xlsxCells(strings.NewReader(sheet), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gridTable(rows, Options{}); err == nil || !strings.Contains(err.Error(), "строка 3") {
+		t.Errorf("ошибка %v, ожидалось упоминание строки 3", err)
+	}
+}
@@ -0,0 +1,149 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"classification-project/internal/models"
+)
+
+// Форматы записи таблиц
+const (
+	FormatTXT  = "txt"  // метки в кавычках, разделитель - табуляция (как входные файлы)
+	FormatCSV  = "csv"  // CSV с пустой ячейкой в углу
+	FormatJSON = "json" // models.Table с тегами json, пропуски - в маске
+)
+
+// Formats - поддерживаемые форматы
+var Formats = []string{FormatTXT, FormatCSV, FormatJSON}
+
+// missingValue - запись пропущенного значения в текстовых форматах
+const missingValue = "NaN"
+
+// FormatFromExt определяет формат по расширению файла (по умолчанию txt)
+func FormatFromExt(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	}
+	return FormatTXT
+}
+
+// WriteTableToFile записывает таблицу в файл. Пустой format выбирается по расширению
+func WriteTableToFile(filename string, t *models.Table, format string) error {
+	if format == "" {
+		format = FormatFromExt(filename)
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := WriteTable(file, t, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteTable записывает таблицу в w в заданном формате. Значения пишутся
+// с полной точностью, поэтому ReadTableFromFile восстанавливает таблицу без потерь
+func WriteTable(w io.Writer, t *models.Table, format string) error {
+	switch format {
+	case FormatTXT:
+		return writeTXT(w, t)
+	case FormatCSV:
+		return writeCSV(w, t)
+	case FormatJSON:
+		return writeJSON(w, t)
+	}
+	return errors.New("неизвестный формат таблицы " + strconv.Quote(format) + ", допустимые: " + strings.Join(Formats, ", "))
+}
+
+// writeTXT пишет таблицу в исходном формате: заголовок с отступом и метки в кавычках
+func writeTXT(w io.Writer, t *models.Table) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("    ")
+	for j, label := range t.ColumnLabels {
+		if j > 0 {
+			bw.WriteByte('\t')
+		}
+		bw.WriteString(quote(label))
+	}
+	bw.WriteByte('\n')
+	for i, label := range t.RowLabels {
+		bw.WriteString(quote(label))
+		for j := range t.Columns {
+			bw.WriteByte('\t')
+			bw.WriteString(formatValue(t, i, j))
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// writeCSV пишет таблицу в CSV; метки берутся в кавычки только при необходимости
+func writeCSV(w io.Writer, t *models.Table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{""}, t.ColumnLabels...)); err != nil {
+		return err
+	}
+	record := make([]string, t.Columns+1)
+	for i, label := range t.RowLabels {
+		record[0] = label
+		for j := range t.Columns {
+			record[j+1] = formatValue(t, i, j)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON пишет таблицу как models.Table. NaN не представим в JSON,
+// поэтому пропуски записываются нулями и отмечаются в маске
+func writeJSON(w io.Writer, t *models.Table) error {
+	out := *t
+	out.Data = append([]float64(nil), t.Data...)
+	out.Mask = nil
+	for i := range t.Rows {
+		for j := range t.Columns {
+			if t.Valid(i, j) {
+				continue
+			}
+			if out.Mask == nil {
+				out.Mask = make([]bool, len(out.Data))
+				for k := range out.Mask {
+					out.Mask[k] = true
+				}
+			}
+			out.Data[i*t.Columns+j] = 0
+			out.Mask[i*t.Columns+j] = false
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&out)
+}
+
+// quote заключает метку в кавычки, удваивая кавычки внутри
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func formatValue(t *models.Table, i, j int) string {
+	if !t.Valid(i, j) {
+		return missingValue
+	}
+	return strconv.FormatFloat(t.Get(i, j), 'g', -1, 64)
+}
@@ -0,0 +1,63 @@
+package writer
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"classification-project/internal/interface/reader"
+	"classification-project/internal/models"
+)
+
+func TestWriteTableRoundTrip(t *testing.T) {
+	table := models.NewTable(3, 3,
+		[]float64{0.08903, -1.5e-7, 1.0 / 3, 12345.678, 0, math.Pi, 1e300, -0.1, 2},
+		[]string{"A", `B "quoted"`, "C, m"},
+		[]string{"1005", "1012.5", "label with spaces"})
+	table.SetMissing(1, 1)
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "table."+format)
+			if err := WriteTableToFile(path, table, ""); err != nil {
+				t.Fatal(err)
+			}
+			got, err := reader.ReadTableFromFile(path)
+			if err != nil {
+				t.Fatalf("ошибка чтения: %v", err)
+			}
+			if !reflect.DeepEqual(got.ColumnLabels, table.ColumnLabels) {
+				t.Errorf("метки столбцов: %q, ожидалось %q", got.ColumnLabels, table.ColumnLabels)
+			}
+			if !reflect.DeepEqual(got.RowLabels, table.RowLabels) {
+				t.Errorf("метки строк: %q, ожидалось %q", got.RowLabels, table.RowLabels)
+			}
+			if got.Rows != table.Rows || got.Columns != table.Columns {
+				t.Fatalf("размер %dx%d, ожидалось %dx%d", got.Rows, got.Columns, table.Rows, table.Columns)
+			}
+			for i := range table.Rows {
+				for j := range table.Columns {
+					if got.Valid(i, j) != table.Valid(i, j) {
+						t.Errorf("(%d,%d): валидность %v, ожидалось %v", i, j, got.Valid(i, j), table.Valid(i, j))
+					} else if table.Valid(i, j) && got.Get(i, j) != table.Get(i, j) {
+						t.Errorf("(%d,%d): %v, ожидалось %v", i, j, got.Get(i, j), table.Get(i, j))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteTableTXTMatchesInputFormat(t *testing.T) {
+	// Исходный входной файл после чтения и записи читается в ту же таблицу
+	want := reader.ReadTableOrPanic(filepath.Join("..", "..", "..", "cmd", "algorithm", "d.txt"))
+	path := filepath.Join(t.TempDir(), "d.txt")
+	if err := WriteTableToFile(path, want, FormatTXT); err != nil {
+		t.Fatal(err)
+	}
+	got := reader.ReadTableOrPanic(path)
+	if !reflect.DeepEqual(got, want) {
+		t.Error("таблица изменилась после записи и чтения")
+	}
+}
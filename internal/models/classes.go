@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Class описывает тип аэрозоля
+type Class struct {
+	Name   string // Короткое имя для вывода (d, u, s, ...)
+	File   string // Файл с долями класса
+	Column int    // Столбец в матрице системы, индекс в Cv и в InputParameters.N
+}
+
+// ClassRegistry - набор классов, упорядоченный по столбцам матрицы системы.
+// Все компоненты (решатель, гистограммы, матрица невязок) берут порядок отсюда
+type ClassRegistry struct {
+	classes []Class
+}
+
+// NewClassRegistry проверяет, что столбцы образуют перестановку 0..n-1,
+// а имена не повторяются, и возвращает реестр
+func NewClassRegistry(classes ...Class) (*ClassRegistry, error) {
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("реестр классов пуст")
+	}
+
+	sorted := make([]Class, len(classes))
+	copy(sorted, classes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Column < sorted[j].Column
+	})
+
+	names := make(map[string]bool)
+	for i, c := range sorted {
+		if c.Column != i {
+			return nil, fmt.Errorf("столбцы классов должны идти подряд с нуля, класс %q имеет столбец %d", c.Name, c.Column)
+		}
+		if c.Name == "" {
+			return nil, fmt.Errorf("пустое имя класса в столбце %d", c.Column)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("имя класса %q повторяется", c.Name)
+		}
+		names[c.Name] = true
+	}
+
+	return &ClassRegistry{classes: sorted}, nil
+}
+
+// MustClassRegistry аналогичен NewClassRegistry, но паникует при ошибке
+func MustClassRegistry(classes ...Class) *ClassRegistry {
+	r, err := NewClassRegistry(classes...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// ParseClasses разбирает список классов вида "d,u,s" или "d=dust.txt,m=marine.txt".
+// Порядок в списке задает столбцы; без явного файла используется <имя>.txt
+func ParseClasses(spec string) (*ClassRegistry, error) {
+	var classes []Class
+	for i, item := range strings.Split(spec, ",") {
+		name, file, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			file = name + ".txt"
+		}
+		classes = append(classes, Class{Name: name, File: file, Column: i})
+	}
+	return NewClassRegistry(classes...)
+}
+
+// DefaultClasses - стандартный набор классов: пыль, урбан, дым
+var DefaultClasses = MustClassRegistry(
+	Class{Name: "d", File: "d.txt", Column: 0},
+	Class{Name: "u", File: "u.txt", Column: 1},
+	Class{Name: "s", File: "s.txt", Column: 2},
+)
+
+// Len возвращает число классов (неизвестных в системе)
+func (r *ClassRegistry) Len() int {
+	return len(r.classes)
+}
+
+// Classes возвращает классы в порядке столбцов
+func (r *ClassRegistry) Classes() []Class {
+	return r.classes
+}
+
+// Names возвращает имена классов в порядке столбцов
+func (r *ClassRegistry) Names() []string {
+	names := make([]string, len(r.classes))
+	for i, c := range r.classes {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// ByName возвращает класс по имени
+func (r *ClassRegistry) ByName(name string) (Class, bool) {
+	for _, c := range r.classes {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Class{}, false
+}
@@ -1,5 +1,7 @@
 package models
 
+import "math"
+
 type MatrixData struct {
 	Rows    int       `json:"rows"`
 	Columns int       `json:"columns"`
@@ -10,6 +12,9 @@ type Table struct {
 	MatrixData
 	ColumnLabels []string `json:"column_labels"`
 	RowLabels    []string `json:"row_labels"`
+	// Mask - признак валидности значений по строкам (nil - все значения валидны).
+	// Значения NaN считаются пропущенными независимо от маски
+	Mask []bool `json:"mask,omitempty"`
 }
 
 func NewMatrix(rows, columns int, data []float64) *MatrixData {
@@ -69,3 +74,58 @@ func (m *Table) SetWithLabels(row, col int, val float64, rowlabel string, collab
 	m.RowLabels[row] = rowlabel
 	m.ColumnLabels[col] = collabel
 }
+
+// Sub возвращает копию подтаблицы [r1:r2, c1:c2] (границы включительно) вместе с метками
+func (m *Table) Sub(r1, c1, r2, c2 int) *Table {
+	if r1 < 0 || c1 < 0 || r2 >= m.Rows || c2 >= m.Columns || r1 > r2 || c1 > c2 {
+		panic("index out of bounds")
+	}
+	rows, cols := r2-r1+1, c2-c1+1
+	data := make([]float64, 0, rows*cols)
+	for i := r1; i <= r2; i++ {
+		data = append(data, m.Data[i*m.Columns+c1:i*m.Columns+c2+1]...)
+	}
+	sub := NewTable(rows, cols, data,
+		append([]string(nil), m.ColumnLabels[c1:c2+1]...),
+		append([]string(nil), m.RowLabels[r1:r2+1]...))
+	if m.Mask != nil {
+		sub.Mask = make([]bool, 0, rows*cols)
+		for i := r1; i <= r2; i++ {
+			sub.Mask = append(sub.Mask, m.Mask[i*m.Columns+c1:i*m.Columns+c2+1]...)
+		}
+	}
+	return sub
+}
+
+// Valid проверяет, что значение в ячейке не пропущено
+func (m *Table) Valid(row, col int) bool {
+	if math.IsNaN(m.Get(row, col)) {
+		return false
+	}
+	return m.Mask == nil || m.Mask[row*m.Columns+col]
+}
+
+// SetMissing помечает ячейку как пропущенную; значение заменяется на NaN
+func (m *Table) SetMissing(row, col int) {
+	if m.Mask == nil {
+		m.Mask = make([]bool, len(m.Data))
+		for i := range m.Mask {
+			m.Mask[i] = true
+		}
+	}
+	m.Set(row, col, math.NaN())
+	m.Mask[row*m.Columns+col] = false
+}
+
+// NumValid возвращает число непропущенных значений
+func (m *Table) NumValid() int {
+	n := 0
+	for i := range m.Rows {
+		for j := range m.Columns {
+			if m.Valid(i, j) {
+				n++
+			}
+		}
+	}
+	return n
+}
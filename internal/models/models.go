@@ -1,29 +1,55 @@
 package models
 
-const (
-	Dust int = iota
-	Smoke
-	Urban
-	Beta
-	Volume
-	Total
-)
-
-const (
-	TotalCv = 3
-)
+type InputParameters struct {
+	Classes         *ClassRegistry // Классы и их порядок в матрице системы
+	N               []*Table       // Доли вкладов, N[i] соответствует классу в столбце i
+	Beta            *Table         // Коэффициент обратного рассеяния
+	Volume          *Table         // Объемная концентрация
+	Selection       []bool         // Маска выбранных точек по строкам (nil - все точки)
+	SigmaN          []*Table       // Погрешности долей (nil или nil-элемент - не заданы)
+	SigmaBeta       *Table         // Погрешность β (nil - не задана)
+	SigmaVolume     *Table         // Погрешность V (nil - не задана)
+	Weighting       string         // Взвешивание строк системы (unit, sigma, both)
+	NPoints         int            // Число точек для составления системы уравнений
+	NIters          int            // Число итераций Монте-Карло
+	NWorkers        int            // Число потоков для параллельной обработки
+	NumPointsToAvg  int            // количество решений для усреднения
+	Lambda          float64        // Параметр регуляризации
+	LambdaMethod    string         // Способ выбора λ (fixed, lcurve, gcv, discrepancy)
+	LambdaScope     string         // Выбор λ для каждой выборки (draw) или по всей области (pooled)
+	NoiseLevel      float64        // Относительный уровень шума V/β для принципа невязки
+	Method          string         // Метод решения линейной системы (chol, qr, svd, lu, nm, nnls)
+	Metric          string         // Метрика невязки для ранжирования решений
+	NBootstrap      int            // Число бутстреп-выборок (0 - без бутстрепа)
+	BootstrapMethod string         // Способ построения интервалов (percentile, bca)
+	MCMCConfig      string         // Путь к JSON-конфигурации MCMC (пусто - без MCMC)
+	Debug           bool           // Флаг отладки
+	MinSize         int            // Минимальный размер области
+	RegionStep      int            // Шаг грубой сетки поиска области (0 - полный перебор)
+	RegionObjective string         // Критерий выбора области (pair, max, mean, det, cond)
+	RegionTop       int            // Число выводимых областей-кандидатов
+	RegionCorr      string         // Коэффициент корреляции для поиска области (pearson, spearman, kendall)
+	Seed            int64          // Зерно генератора случайных чисел
+}
 
-var ClassificationName = [...]string{"d", "s", "u"}
+// Tables возвращает все входные таблицы: доли классов, затем Beta и Volume
+func (p *InputParameters) Tables() []*Table {
+	tables := make([]*Table, 0, len(p.N)+2)
+	tables = append(tables, p.N...)
+	return append(tables, p.Beta, p.Volume)
+}
 
-type InputParameters struct {
-	N              [Total]*Table // Доли вкладов
-	NPoints        int           // Число точек для составления системы уравнений
-	NIters         int           // Число итераций Монте-Карло
-	NWorkers       int           // Число потоков для параллельной обработки
-	NumPointsToAvg int           // количество решений для усреднения
-	Lambda         float64       // Параметр регуляризации
-	Debug          bool          // Флаг отладки
-	MinSize        int           // Минимальный размер области
+// HasSigma сообщает, задана ли хотя бы одна таблица погрешностей
+func (p *InputParameters) HasSigma() bool {
+	if p.SigmaBeta != nil || p.SigmaVolume != nil {
+		return true
+	}
+	for _, s := range p.SigmaN {
+		if s != nil {
+			return true
+		}
+	}
+	return false
 }
 
 type DataPacket struct {
@@ -33,7 +59,7 @@ type DataPacket struct {
 }
 
 type ProcessResult struct {
-	Cv [TotalCv]float64
+	Cv []float64
 }
 
 type Index struct {
@@ -42,6 +68,14 @@ type Index struct {
 }
 
 type OutputSolution struct {
-	Cv          []float64
-	Discrepancy float64
+	Cv           []float64
+	CvErr        []float64 // Стандартные ошибки Cv по таблицам погрешностей (nil - не заданы)
+	Discrepancy  float64
+	Metric       string  // Метрика, которой посчитана Discrepancy
+	Method       string  // Метод, которым получено решение
+	ResidualNorm float64 // Норма невязки сбалансированной системы
+	Cond         float64 // Число обусловленности сбалансированной матрицы
+	Rank         int     // Численный ранг сбалансированной матрицы
+	Lambda       float64 // Использованный параметр регуляризации
+	Active       []bool  // Активные ограничения Cv[i] >= 0 (только для NNLS)
 }
@@ -0,0 +1,274 @@
+package statistics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Способы построения бутстреп-интервалов
+const (
+	BootstrapPercentile = "percentile" // процентильный интервал
+	BootstrapBCa        = "bca"        // интервал с поправкой на смещение и ускорение
+)
+
+// maxJackknifeGroups - максимальное число групп в джекнайфе для оценки ускорения BCa.
+// При большем числе точек они удаляются группами подряд идущих индексов
+const maxJackknifeGroups = 200
+
+// BootstrapEstimator вычисляет вектор параметров по выборке точек с заданными индексами
+type BootstrapEstimator func(indices []int) ([]float64, error)
+
+// ConfidenceInterval - доверительный интервал для одного параметра
+type ConfidenceInterval struct {
+	Level float64 // доверительная вероятность, например 0.95
+	Lower float64
+	Upper float64
+}
+
+// BootstrapResult содержит бутстреп-оценки для каждого параметра
+type BootstrapResult struct {
+	Method   string
+	Estimate []float64 // оценка по исходной выборке
+	Mean     []float64 // среднее по бутстреп-выборкам
+	StdErr   []float64 // стандартная ошибка
+	CI68     []ConfidenceInterval
+	CI95     []ConfidenceInterval
+	Cov      *mat.SymDense // ковариационная матрица параметров
+	NValid   int           // число успешных бутстреп-выборок
+	Samples  [][]float64   // бутстреп-оценки, Samples[k][i] - параметр i в выборке k
+}
+
+// Bootstrap оценивает неопределенность параметров, повторно вычисляя их по
+// выборкам из n точек с возвращением. Выборки распределяются по nWorkers потокам,
+// зерна выборок берутся из rng заранее, поэтому результат не зависит от числа потоков
+func Bootstrap(n, nBoot int, method string, rng *rand.Rand, nWorkers int, estimator BootstrapEstimator) (BootstrapResult, error) {
+	if method != BootstrapPercentile && method != BootstrapBCa {
+		return BootstrapResult{}, fmt.Errorf("неизвестный способ бутстрепа %q, допустимые: %s, %s",
+			method, BootstrapPercentile, BootstrapBCa)
+	}
+	if n < 2 || nBoot < 2 {
+		return BootstrapResult{}, fmt.Errorf("недостаточно точек (%d) или бутстреп-выборок (%d)", n, nBoot)
+	}
+
+	all := make([]int, n)
+	for i := range all {
+		all[i] = i
+	}
+	estimate, err := estimator(all)
+	if err != nil {
+		return BootstrapResult{}, fmt.Errorf("оценка по исходной выборке: %v", err)
+	}
+	p := len(estimate)
+
+	seeds := make([]int64, nBoot)
+	for i := range seeds {
+		seeds[i] = rng.Int63()
+	}
+	replicates := make([][]float64, nBoot)
+	parallelFor(nBoot, nWorkers, func(k int) {
+		local := rand.New(rand.NewSource(seeds[k]))
+		indices := make([]int, n)
+		for i := range indices {
+			indices[i] = local.Intn(n)
+		}
+		if theta, err := estimator(indices); err == nil {
+			replicates[k] = theta
+		}
+	})
+
+	samples := make([][]float64, 0, nBoot)
+	for _, theta := range replicates {
+		if theta != nil {
+			samples = append(samples, theta)
+		}
+	}
+	if len(samples) < 2 {
+		return BootstrapResult{}, fmt.Errorf("успешных бутстреп-выборок: %d", len(samples))
+	}
+
+	res := BootstrapResult{
+		Method:   method,
+		Estimate: estimate,
+		Mean:     make([]float64, p),
+		StdErr:   make([]float64, p),
+		CI68:     make([]ConfidenceInterval, p),
+		CI95:     make([]ConfidenceInterval, p),
+		Cov:      mat.NewSymDense(p, nil),
+		NValid:   len(samples),
+		Samples:  samples,
+	}
+
+	nb := float64(len(samples))
+	for _, theta := range samples {
+		for i := range p {
+			res.Mean[i] += theta[i] / nb
+		}
+	}
+	for i := range p {
+		for j := i; j < p; j++ {
+			cov := 0.0
+			for _, theta := range samples {
+				cov += (theta[i] - res.Mean[i]) * (theta[j] - res.Mean[j])
+			}
+			res.Cov.SetSym(i, j, cov/(nb-1))
+		}
+		res.StdErr[i] = math.Sqrt(res.Cov.At(i, i))
+	}
+
+	// Для BCa нужны поправка на смещение z0 и ускорение a
+	z0 := make([]float64, p)
+	accel := make([]float64, p)
+	if method == BootstrapBCa {
+		accel, err = jackknifeAcceleration(n, p, nWorkers, estimator)
+		if err != nil {
+			return BootstrapResult{}, err
+		}
+		for i := range p {
+			below := 0
+			for _, theta := range samples {
+				if theta[i] < estimate[i] {
+					below++
+				}
+			}
+			// Ограничиваем долю, чтобы квантиль оставался конечным
+			frac := math.Min(math.Max(float64(below)/nb, 0.5/nb), 1-0.5/nb)
+			z0[i] = distuv.UnitNormal.Quantile(frac)
+		}
+	}
+
+	column := make([]float64, len(samples))
+	for i := range p {
+		for k, theta := range samples {
+			column[k] = theta[i]
+		}
+		sort.Float64s(column)
+		res.CI68[i] = interval(column, 0.68, z0[i], accel[i])
+		res.CI95[i] = interval(column, 0.95, z0[i], accel[i])
+	}
+
+	return res, nil
+}
+
+// interval строит интервал по отсортированным бутстреп-оценкам.
+// При z0 = a = 0 получается процентильный интервал, иначе - BCa
+func interval(sorted []float64, level, z0, a float64) ConfidenceInterval {
+	adjust := func(alpha float64) float64 {
+		z := distuv.UnitNormal.Quantile(alpha)
+		return distuv.UnitNormal.CDF(z0 + (z0+z)/(1-a*(z0+z)))
+	}
+	alpha := (1 - level) / 2
+	return ConfidenceInterval{
+		Level: level,
+		Lower: Quantile(sorted, adjust(alpha)),
+		Upper: Quantile(sorted, adjust(1-alpha)),
+	}
+}
+
+// Quantile возвращает квантиль уровня q отсортированной выборки
+// с линейной интерполяцией
+func Quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	if lo < 0 {
+		return sorted[0]
+	}
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(lo)
+	return sorted[lo]*(1-frac) + sorted[lo+1]*frac
+}
+
+// jackknifeAcceleration оценивает ускорение BCa по джекнайфу:
+// a = Σ(θ̄ - θ₍ᵢ₎)³ / (6 (Σ(θ̄ - θ₍ᵢ₎)²)^{3/2})
+func jackknifeAcceleration(n, p, nWorkers int, estimator BootstrapEstimator) ([]float64, error) {
+	groups := min(n, maxJackknifeGroups)
+	thetas := make([][]float64, groups)
+	errs := make([]error, groups)
+	parallelFor(groups, nWorkers, func(g int) {
+		// Удаляем группу точек [lo, hi)
+		lo, hi := g*n/groups, (g+1)*n/groups
+		indices := make([]int, 0, n-(hi-lo))
+		for i := range n {
+			if i < lo || i >= hi {
+				indices = append(indices, i)
+			}
+		}
+		thetas[g], errs[g] = estimator(indices)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("джекнайф: %v", err)
+		}
+	}
+
+	accel := make([]float64, p)
+	for i := range p {
+		mean := 0.0
+		for _, theta := range thetas {
+			mean += theta[i] / float64(groups)
+		}
+		var num, den float64
+		for _, theta := range thetas {
+			d := mean - theta[i]
+			num += d * d * d
+			den += d * d
+		}
+		if den > 0 {
+			accel[i] = num / (6 * math.Pow(den, 1.5))
+		}
+	}
+	return accel, nil
+}
+
+// parallelFor выполняет body(i) для i в [0, n) на nWorkers потоках
+func parallelFor(n, nWorkers int, body func(i int)) {
+	nWorkers = max(1, min(nWorkers, n))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range nWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				body(i)
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// PrintBootstrap выводит бутстреп-оценки коэффициентов и их ковариационную матрицу
+func PrintBootstrap(res BootstrapResult, names []string) {
+	fmt.Printf("=== Бутстреп (%s), успешных выборок: %d ===\n", res.Method, res.NValid)
+	fmt.Printf("%-8s %11s %11s %11s %25s %25s\n", "", "estimate", "mean", "std.err", "68% CI", "95% CI")
+	for i, name := range names {
+		fmt.Printf("%-8s %11.3e %11.3e %11.3e [%11.3e, %11.3e] [%11.3e, %11.3e]\n",
+			"Cv["+name+"]", res.Estimate[i], res.Mean[i], res.StdErr[i],
+			res.CI68[i].Lower, res.CI68[i].Upper, res.CI95[i].Lower, res.CI95[i].Upper)
+	}
+
+	fmt.Println("Ковариационная матрица:")
+	fmt.Printf("%-8s", "")
+	for _, name := range names {
+		fmt.Printf(" %11s", "Cv["+name+"]")
+	}
+	fmt.Println()
+	for i, name := range names {
+		fmt.Printf("%-8s", "Cv["+name+"]")
+		for j := range names {
+			fmt.Printf(" %+11.3e", res.Cov.At(i, j))
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,73 @@
+package statistics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestBootstrapMean(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	n := 400
+	data := make([]float64, n)
+	for i := range data {
+		data[i] = 10 + 2*rng.NormFloat64()
+	}
+	mean := func(indices []int) ([]float64, error) {
+		sum := 0.0
+		for _, i := range indices {
+			sum += data[i]
+		}
+		return []float64{sum / float64(len(indices))}, nil
+	}
+
+	for _, method := range []string{BootstrapPercentile, BootstrapBCa} {
+		t.Run(method, func(t *testing.T) {
+			res, err := Bootstrap(n, 2000, method, rand.New(rand.NewSource(1)), 4, mean)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+
+			// Стандартная ошибка среднего ≈ σ/√n
+			if want := 2 / math.Sqrt(float64(n)); math.Abs(res.StdErr[0]-want) > 0.15*want {
+				t.Errorf("стандартная ошибка: получено %.4f, ожидалось около %.4f", res.StdErr[0], want)
+			}
+			if math.Abs(res.Cov.At(0, 0)-res.StdErr[0]*res.StdErr[0]) > 1e-12 {
+				t.Errorf("дисперсия не совпадает с квадратом стандартной ошибки")
+			}
+
+			ci68, ci95 := res.CI68[0], res.CI95[0]
+			if !(ci95.Lower < ci68.Lower && ci68.Lower < res.Estimate[0] &&
+				res.Estimate[0] < ci68.Upper && ci68.Upper < ci95.Upper) {
+				t.Errorf("интервалы не вложены: 68%% %+v, 95%% %+v, оценка %.4f", ci68, ci95, res.Estimate[0])
+			}
+			// Для нормальных данных 95% интервал близок к ±1.96 SE
+			if width := ci95.Upper - ci95.Lower; math.Abs(width-2*1.96*res.StdErr[0]) > 0.15*width {
+				t.Errorf("ширина 95%% интервала %.4f, ожидалось около %.4f", width, 2*1.96*res.StdErr[0])
+			}
+		})
+	}
+}
+
+func TestBootstrapReproducible(t *testing.T) {
+	data := []float64{1, 4, 2, 8, 5, 7, 3, 6, 9, 0}
+	max := func(indices []int) ([]float64, error) {
+		m := math.Inf(-1)
+		for _, i := range indices {
+			m = math.Max(m, data[i])
+		}
+		return []float64{m}, nil
+	}
+
+	a, err := Bootstrap(len(data), 200, BootstrapBCa, rand.New(rand.NewSource(3)), 1, max)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	b, err := Bootstrap(len(data), 200, BootstrapBCa, rand.New(rand.NewSource(3)), 8, max)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if a.CI95[0] != b.CI95[0] || a.StdErr[0] != b.StdErr[0] {
+		t.Errorf("результат зависит от числа потоков: %+v и %+v", a.CI95[0], b.CI95[0])
+	}
+}
@@ -0,0 +1,54 @@
+package statistics
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestCorr2SubmatrixNaN(t *testing.T) {
+	nan := math.NaN()
+	A := mat.NewDense(2, 3, []float64{1, 2, nan, 3, 4, 100})
+	B := mat.NewDense(2, 3, []float64{2, 4, 5, 6, nan, -100})
+
+	got, err := Corr2Submatrix(A, B, 0, 0, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Corr2WithNaNHandling(mat.NewDense(2, 2, []float64{1, 2, 3, 4}), mat.NewDense(2, 2, []float64{2, 4, 6, nan}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got-want) > 1e-12 {
+		t.Errorf("корреляция %v, ожидалось %v", got, want)
+	}
+}
+
+func TestFindMaxAreaMinCorrelationSkipsNaN(t *testing.T) {
+	// Столбцы 0-1 некоррелированы; в столбце 2 нет ни одной пары без пропусков,
+	// поэтому он не увеличивает площадь и выбирается область из столбцов 0-1
+	nan := math.NaN()
+	A := mat.NewDense(4, 3, []float64{
+		1, 2, nan,
+		2, 1, nan,
+		1, 2, 10,
+		2, 1, nan,
+	})
+	B := mat.NewDense(4, 3, []float64{
+		1, 1, nan,
+		2, 2, nan,
+		2, 2, nan,
+		1, 1, 10,
+	})
+	r1, c1, r2, c2, corr, err := FindMaxAreaMinCorrelation(A, B, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corr > 1e-10 {
+		t.Errorf("|corr| = %v, ожидалось 0", corr)
+	}
+	if r1 != 0 || c1 != 0 || r2 != 3 || c2 != 1 {
+		t.Errorf("область [%d:%d, %d:%d], ожидалась [0:3, 0:1]", r1, r2, c1, c2)
+	}
+}
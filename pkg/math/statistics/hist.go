@@ -24,8 +24,24 @@ type HistogramResult struct {
 	DataName   string // название данных (например, "Discrepancy", "Cv[0]")
 }
 
-// CalculateHistograms вычисляет гистограммы для всех данных
-func CalculateHistograms(solutions []models.OutputSolution, numBins int) map[string]HistogramResult {
+// CvHistogramName возвращает ключ гистограммы коэффициента класса
+func CvHistogramName(className string) string {
+	return fmt.Sprintf("Cv[%s]", className)
+}
+
+// HistogramNames возвращает ключи гистограмм в порядке вывода:
+// сначала Discrepancy, затем классы в порядке столбцов реестра
+func HistogramNames(classes *models.ClassRegistry) []string {
+	names := []string{"Discrepancy"}
+	for _, c := range classes.Classes() {
+		names = append(names, CvHistogramName(c.Name))
+	}
+	return names
+}
+
+// CalculateHistograms вычисляет гистограммы для всех данных.
+// Cv[i] подписывается именем класса, стоящего в реестре в столбце i
+func CalculateHistograms(solutions []models.OutputSolution, classes *models.ClassRegistry, numBins int) map[string]HistogramResult {
 	results := make(map[string]HistogramResult)
 
 	if len(solutions) == 0 {
@@ -45,30 +61,25 @@ func CalculateHistograms(solutions []models.OutputSolution, numBins int) map[str
 	)
 
 	// 2. Гистограммы для каждого элемента Cv
-	if len(solutions) > 0 && len(solutions[0].Cv) > 0 {
-		cvLength := len(solutions[0].Cv)
-
-		// Проверяем, что все Cv имеют одинаковую длину
-		for i := 1; i < len(solutions); i++ {
-			if len(solutions[i].Cv) != cvLength {
-				panic("Все Cv должны иметь одинаковую длину")
-			}
+	// Проверяем, что все Cv соответствуют реестру классов
+	for i := range solutions {
+		if len(solutions[i].Cv) != classes.Len() {
+			panic("Все Cv должны иметь длину, равную числу классов")
 		}
+	}
 
-		// Для каждого индекса Cv создаем отдельную гистограмму
-		for cvIndex := 0; cvIndex < cvLength; cvIndex++ {
-			idxName := models.ClassificationName[cvIndex]
-			cvData := make([]float64, len(solutions))
-			for i, sol := range solutions {
-				cvData[i] = sol.Cv[cvIndex]
-			}
-
-			results[fmt.Sprintf("Cv[%s]", idxName)] = calculateSingleHistogram(
-				cvData,
-				numBins,
-				fmt.Sprintf("Cv[%s]", idxName),
-			)
+	// Для каждого класса создаем отдельную гистограмму
+	for _, c := range classes.Classes() {
+		cvData := make([]float64, len(solutions))
+		for i, sol := range solutions {
+			cvData[i] = sol.Cv[c.Column]
 		}
+
+		results[CvHistogramName(c.Name)] = calculateSingleHistogram(
+			cvData,
+			numBins,
+			CvHistogramName(c.Name),
+		)
 	}
 
 	return results
@@ -215,6 +226,16 @@ func PrintAllHistograms(results map[string]HistogramResult, maxBarWidth int) {
 	}
 }
 
+// PrintHistogramsInOrder выводит гистограммы в заданном порядке ключей
+func PrintHistogramsInOrder(results map[string]HistogramResult, names []string, maxBarWidth int) {
+	for _, name := range names {
+		if result, ok := results[name]; ok {
+			PrintHistogram(result, maxBarWidth)
+			fmt.Println()
+		}
+	}
+}
+
 // GetHistogramStatistics возвращает статистику по гистограмме
 func GetHistogramStatistics(result HistogramResult) map[string]float64 {
 	stats := make(map[string]float64)
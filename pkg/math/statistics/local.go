@@ -0,0 +1,38 @@
+package statistics
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// LocalCorrelation возвращает для каждой точки максимум |r_ij| по всем парам
+// таблиц в окне (2*radius+1) x (2*radius+1) с центром в точке (у краев окно
+// обрезается). Учитываются точки, заданные во всех таблицах; если таких
+// точек в окне меньше трех, результат - NaN
+func LocalCorrelation(tables []*mat.Dense, radius int) *mat.Dense {
+	sums := newMultiSums(tables)
+	k := sums.k
+	rows, cols := sums.rows, sums.cols
+	res := mat.NewDense(rows, cols, nil)
+	corr, means := make([]float64, k*k), make([]float64, k)
+	for i := range rows {
+		for j := range cols {
+			r1, c1 := max(i-radius, 0), max(j-radius, 0)
+			r2, c2 := min(i+radius, rows-1), min(j+radius, cols-1)
+			n, ok := sums.corr(r1, c1, r2, c2, corr, means)
+			if !ok || n < 3 {
+				res.Set(i, j, math.NaN())
+				continue
+			}
+			worst := 0.0
+			for a := range k {
+				for b := a + 1; b < k; b++ {
+					worst = math.Max(worst, math.Abs(corr[a*k+b]))
+				}
+			}
+			res.Set(i, j, worst)
+		}
+	}
+	return res
+}
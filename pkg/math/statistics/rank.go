@@ -0,0 +1,281 @@
+package statistics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Коэффициенты корреляции для поиска областей
+const (
+	CorrelationPearson  = "pearson"  // линейная корреляция Пирсона
+	CorrelationSpearman = "spearman" // ранговая корреляция Спирмена
+	CorrelationKendall  = "kendall"  // τ-b Кендалла
+)
+
+// Correlations перечисляет все коэффициенты корреляции
+var Correlations = []string{CorrelationPearson, CorrelationSpearman, CorrelationKendall}
+
+// CorrFunc вычисляет корреляцию подматриц [r1:r2, c1:c2] (границы включительно)
+// по парам без NaN и возвращает число учтенных пар. Если обе подматрицы
+// постоянны, корреляция равна 1, если одна - 0; при числе пар <= 1 - ошибка
+type CorrFunc func(A, B *mat.Dense, r1, c1, r2, c2 int) (corr float64, n int, err error)
+
+// CorrelationFunc возвращает функцию корреляции подматриц по имени (см. Correlations)
+func CorrelationFunc(name string) (CorrFunc, error) {
+	switch name {
+	case CorrelationPearson:
+		return corr2SubmatrixCount, nil
+	case CorrelationSpearman:
+		return spearmanSubmatrixCount, nil
+	case CorrelationKendall:
+		return kendallSubmatrixCount, nil
+	}
+	return nil, fmt.Errorf("неизвестный коэффициент корреляции %q, допустимые: %s", name, strings.Join(Correlations, ", "))
+}
+
+// Spearman вычисляет ранговую корреляцию Спирмена двух матриц одинакового
+// размера: корреляцию Пирсона средних рангов (равным значениям назначается
+// средний ранг). Как и Corr2, возвращает ошибку, если постоянна одна из матриц
+func Spearman(A, B *mat.Dense) (float64, error) {
+	return rankCorr(A, B, false, spearman)
+}
+
+// SpearmanWithNaNHandling - версия Spearman с обработкой NaN значений:
+// пары, в которых хотя бы одно значение NaN, не учитываются
+func SpearmanWithNaNHandling(A, B *mat.Dense) (float64, error) {
+	return rankCorr(A, B, true, spearman)
+}
+
+// SpearmanSubmatrix вычисляет корреляцию Спирмена для подматриц, пары с NaN
+// не учитываются (см. Corr2Submatrix)
+func SpearmanSubmatrix(A, B *mat.Dense, r1, c1, r2, c2 int) (float64, error) {
+	corr, _, err := spearmanSubmatrixCount(A, B, r1, c1, r2, c2)
+	return corr, err
+}
+
+// Kendall вычисляет τ-b Кендалла двух матриц одинакового размера с поправкой
+// на связанные значения. Как и Corr2, возвращает ошибку, если постоянна одна
+// из матриц
+func Kendall(A, B *mat.Dense) (float64, error) {
+	return rankCorr(A, B, false, kendall)
+}
+
+// KendallWithNaNHandling - версия Kendall с обработкой NaN значений
+func KendallWithNaNHandling(A, B *mat.Dense) (float64, error) {
+	return rankCorr(A, B, true, kendall)
+}
+
+// KendallSubmatrix вычисляет τ-b Кендалла для подматриц, пары с NaN
+// не учитываются (см. Corr2Submatrix)
+func KendallSubmatrix(A, B *mat.Dense, r1, c1, r2, c2 int) (float64, error) {
+	corr, _, err := kendallSubmatrixCount(A, B, r1, c1, r2, c2)
+	return corr, err
+}
+
+func spearmanSubmatrixCount(A, B *mat.Dense, r1, c1, r2, c2 int) (float64, int, error) {
+	return rankSubmatrix(A, B, r1, c1, r2, c2, spearman)
+}
+
+func kendallSubmatrixCount(A, B *mat.Dense, r1, c1, r2, c2 int) (float64, int, error) {
+	return rankSubmatrix(A, B, r1, c1, r2, c2, kendall)
+}
+
+// rankFunc вычисляет ранговую корреляцию пар (x[i], y[i]) без NaN и
+// сообщает, постоянны ли x и y
+type rankFunc func(x, y []float64) (corr float64, constX, constY bool)
+
+// rankCorr - общая часть Spearman и Kendall для матриц целиком
+func rankCorr(A, B *mat.Dense, skipNaN bool, f rankFunc) (float64, error) {
+	ra, ca := A.Dims()
+	rb, cb := B.Dims()
+	if ra != rb || ca != cb {
+		return 0, fmt.Errorf("матрицы должны иметь одинаковые размеры: A(%dx%d), B(%dx%d)", ra, ca, rb, cb)
+	}
+	var x, y []float64
+	for i := range ra {
+		for j := range ca {
+			a, b := A.At(i, j), B.At(i, j)
+			if math.IsNaN(a) || math.IsNaN(b) {
+				if !skipNaN {
+					return math.NaN(), fmt.Errorf("значение NaN в точке (%d, %d), используйте версию с обработкой NaN", i, j)
+				}
+				continue
+			}
+			x, y = append(x, a), append(y, b)
+		}
+	}
+	if len(x) == 0 {
+		return math.NaN(), fmt.Errorf("нет валидных пар значений для расчета корреляции")
+	}
+	corr, constX, constY := f(x, y)
+	if constX || constY {
+		if constX && constY {
+			return 1.0, nil
+		}
+		return 0, fmt.Errorf("один из наборов данных постоянный, корреляция не определена")
+	}
+	return corr, nil
+}
+
+// rankSubmatrix - общая часть SpearmanSubmatrix и KendallSubmatrix с правилами
+// corr2SubmatrixCount для малых и постоянных подматриц
+func rankSubmatrix(A, B *mat.Dense, r1, c1, r2, c2 int, f rankFunc) (float64, int, error) {
+	var x, y []float64
+	for i := r1; i <= r2; i++ {
+		for j := c1; j <= c2; j++ {
+			a, b := A.At(i, j), B.At(i, j)
+			if math.IsNaN(a) || math.IsNaN(b) {
+				continue
+			}
+			x, y = append(x, a), append(y, b)
+		}
+	}
+	n := len(x)
+	if n <= 1 {
+		return 0, n, fmt.Errorf("подматрица слишком мала")
+	}
+	corr, constX, constY := f(x, y)
+	if constX || constY {
+		if constX && constY {
+			return 1.0, n, nil
+		}
+		return 0, n, nil
+	}
+	return corr, n, nil
+}
+
+// ranks возвращает средние ранги значений x (1..n)
+func ranks(x []float64) []float64 {
+	idx := make([]int, len(x))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return x[idx[a]] < x[idx[b]] })
+	r := make([]float64, len(x))
+	for start := 0; start < len(idx); {
+		end := start + 1
+		for end < len(idx) && x[idx[end]] == x[idx[start]] {
+			end++
+		}
+		// Ранги start+1..end заменяются средним
+		avg := float64(start+end+1) / 2
+		for _, i := range idx[start:end] {
+			r[i] = avg
+		}
+		start = end
+	}
+	return r
+}
+
+// spearman - корреляция Пирсона средних рангов
+func spearman(x, y []float64) (float64, bool, bool) {
+	rx, ry := ranks(x), ranks(y)
+	// Средний ранг равен (n+1)/2 независимо от связей
+	mean := float64(len(x)+1) / 2
+	var sxy, sxx, syy float64
+	for i := range rx {
+		dx, dy := rx[i]-mean, ry[i]-mean
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0, sxx == 0, syy == 0
+	}
+	return sxy / math.Sqrt(sxx*syy), false, false
+}
+
+// kendall вычисляет τ-b за O(n log n) алгоритмом Найта: пары сортируются по
+// (x, y), а число несогласованных пар равно числу инверсий y, подсчитанному
+// сортировкой слиянием
+func kendall(x, y []float64) (float64, bool, bool) {
+	n := len(x)
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool {
+		i, j := idx[a], idx[b]
+		if x[i] != x[j] {
+			return x[i] < x[j]
+		}
+		return y[i] < y[j]
+	})
+
+	// Связи по x (n1) и одновременно по x и y (n3)
+	var n1, n3 float64
+	for start := 0; start < n; {
+		end := start + 1
+		for end < n && x[idx[end]] == x[idx[start]] {
+			end++
+		}
+		t := float64(end - start)
+		n1 += t * (t - 1) / 2
+		for s := start; s < end; {
+			e := s + 1
+			for e < end && y[idx[e]] == y[idx[s]] {
+				e++
+			}
+			u := float64(e - s)
+			n3 += u * (u - 1) / 2
+			s = e
+		}
+		start = end
+	}
+
+	ys := make([]float64, n)
+	for k, i := range idx {
+		ys[k] = y[i]
+	}
+	swaps := float64(mergeInversions(ys, make([]float64, n)))
+
+	// После сортировки слиянием ys упорядочены: связи по y (n2)
+	var n2 float64
+	for start := 0; start < n; {
+		end := start + 1
+		for end < n && ys[end] == ys[start] {
+			end++
+		}
+		t := float64(end - start)
+		n2 += t * (t - 1) / 2
+		start = end
+	}
+
+	n0 := float64(n) * float64(n-1) / 2
+	if n0 == n1 || n0 == n2 {
+		return 0, n0 == n1, n0 == n2
+	}
+	s := n0 - n1 - n2 + n3 - 2*swaps
+	return s / math.Sqrt((n0-n1)*(n0-n2)), false, false
+}
+
+// mergeInversions сортирует a слиянием (buf - буфер той же длины) и
+// возвращает число пар i < j с a[i] > a[j]
+func mergeInversions(a, buf []float64) int {
+	n := len(a)
+	if n < 2 {
+		return 0
+	}
+	mid := n / 2
+	inv := mergeInversions(a[:mid], buf[:mid]) + mergeInversions(a[mid:], buf[mid:])
+	i, j, k := 0, mid, 0
+	for i < mid && j < n {
+		if a[j] < a[i] {
+			buf[k] = a[j]
+			inv += mid - i
+			j++
+		} else {
+			buf[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(buf[k:], a[i:mid])
+	copy(buf[k:], a[j:])
+	copy(a, buf[:n])
+	return inv
+}
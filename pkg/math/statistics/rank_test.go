@@ -0,0 +1,196 @@
+package statistics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// bruteKendall вычисляет τ-b перебором всех пар
+func bruteKendall(x, y []float64) float64 {
+	var s, tx, ty, n0 float64
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			dx, dy := x[i]-x[j], y[i]-y[j]
+			n0++
+			if dx == 0 {
+				tx++
+			}
+			if dy == 0 {
+				ty++
+			}
+			s += sign(dx) * sign(dy)
+		}
+	}
+	return s / math.Sqrt((n0-tx)*(n0-ty))
+}
+
+func sign(v float64) float64 {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+// bruteRanks вычисляет средние ранги подсчетом меньших и равных значений
+func bruteRanks(x []float64) []float64 {
+	r := make([]float64, len(x))
+	for i := range x {
+		less, equal := 0, 0
+		for j := range x {
+			if x[j] < x[i] {
+				less++
+			} else if x[j] == x[i] {
+				equal++
+			}
+		}
+		r[i] = float64(less) + float64(equal+1)/2
+	}
+	return r
+}
+
+func TestRankCorrelations(t *testing.T) {
+	A := mat.NewDense(1, 5, []float64{1, 2, 3, 4, 5})
+	B := mat.NewDense(1, 5, []float64{2, 1, 4, 3, 5})
+	if got, err := Spearman(A, B); err != nil || math.Abs(got-0.8) > 1e-12 {
+		t.Errorf("Spearman = %v, %v, ожидалось 0.8", got, err)
+	}
+	if got, err := Kendall(A, B); err != nil || math.Abs(got-0.6) > 1e-12 {
+		t.Errorf("Kendall = %v, %v, ожидалось 0.6", got, err)
+	}
+
+	// Монотонное преобразование и выброс не меняют ранговые корреляции
+	C := mat.NewDense(1, 5, []float64{1, 8, 27, 64, 1e6})
+	for name, f := range map[string]func(A, B *mat.Dense) (float64, error){"Spearman": Spearman, "Kendall": Kendall} {
+		if got, err := f(A, C); err != nil || math.Abs(got-1) > 1e-12 {
+			t.Errorf("%s монотонных матриц = %v, %v", name, got, err)
+		}
+	}
+
+	// Случайные данные со связями сравниваются с прямым расчетом
+	rng := rand.New(rand.NewSource(3))
+	for trial := range 20 {
+		n := 2 + rng.Intn(30)
+		x, y := make([]float64, n), make([]float64, n)
+		for i := range x {
+			x[i], y[i] = float64(rng.Intn(5)), float64(rng.Intn(4))
+		}
+		X, Y := mat.NewDense(1, n, x), mat.NewDense(1, n, y)
+		want, wantErr := Corr2(mat.NewDense(1, n, bruteRanks(x)), mat.NewDense(1, n, bruteRanks(y)))
+		got, err := Spearman(X, Y)
+		if (err != nil) != (wantErr != nil) || err == nil && math.Abs(got-want) > 1e-12 {
+			t.Errorf("испытание %d: Spearman = %v (%v), ожидалось %v (%v)", trial, got, err, want, wantErr)
+		}
+		got, err = Kendall(X, Y)
+		if err == nil {
+			if want := bruteKendall(x, y); math.Abs(got-want) > 1e-12 {
+				t.Errorf("испытание %d: Kendall = %v, ожидалось %v", trial, got, want)
+			}
+		}
+	}
+}
+
+func TestRankCorrelationsNaN(t *testing.T) {
+	nan := math.NaN()
+	A := mat.NewDense(2, 3, []float64{1, 2, nan, 3, 4, 100})
+	B := mat.NewDense(2, 3, []float64{2, 4, 5, 6, nan, -100})
+	cases := []struct {
+		name      string
+		full      func(A, B *mat.Dense) (float64, error)
+		nanSafe   func(A, B *mat.Dense) (float64, error)
+		submatrix func(A, B *mat.Dense, r1, c1, r2, c2 int) (float64, error)
+	}{
+		{"Spearman", Spearman, SpearmanWithNaNHandling, SpearmanSubmatrix},
+		{"Kendall", Kendall, KendallWithNaNHandling, KendallSubmatrix},
+	}
+	for _, c := range cases {
+		if _, err := c.full(A, B); err == nil {
+			t.Errorf("%s: ожидалась ошибка для NaN", c.name)
+		}
+		// Пары без NaN: (1, 2), (2, 4), (3, 6), (100, -100)
+		got, err := c.nanSafe(A, B)
+		want, _ := c.full(mat.NewDense(1, 4, []float64{1, 2, 3, 100}), mat.NewDense(1, 4, []float64{2, 4, 6, -100}))
+		if err != nil || math.Abs(got-want) > 1e-12 {
+			t.Errorf("%s с обработкой NaN = %v, %v, ожидалось %v", c.name, got, err, want)
+		}
+		sub, err := c.submatrix(A, B, 0, 0, 1, 1)
+		want, _ = c.full(mat.NewDense(1, 3, []float64{1, 2, 3}), mat.NewDense(1, 3, []float64{2, 4, 6}))
+		if err != nil || math.Abs(sub-want) > 1e-12 {
+			t.Errorf("%s подматрицы = %v, %v, ожидалось %v", c.name, sub, err, want)
+		}
+
+		// Постоянные данные - по правилам Corr2 и corr2SubmatrixCount
+		ones := mat.NewDense(1, 3, []float64{1, 1, 1})
+		inc := mat.NewDense(1, 3, []float64{1, 2, 3})
+		if r, err := c.full(ones, ones); err != nil || r != 1 {
+			t.Errorf("%s постоянных матриц = %v, %v", c.name, r, err)
+		}
+		if _, err := c.full(ones, inc); err == nil {
+			t.Errorf("%s: ожидалась ошибка для постоянной матрицы", c.name)
+		}
+		if r, err := c.submatrix(ones, inc, 0, 0, 0, 2); err != nil || r != 0 {
+			t.Errorf("%s подматрицы с постоянной = %v, %v", c.name, r, err)
+		}
+		if _, err := c.submatrix(A, B, 0, 2, 0, 2); err == nil {
+			t.Errorf("%s: ожидалась ошибка для подматрицы без пар", c.name)
+		}
+	}
+
+	if _, err := CorrelationFunc("pearson2"); err == nil {
+		t.Error("ожидалась ошибка для неизвестного коэффициента")
+	}
+}
+
+// TestFindRegionsRankCorrelation сравнивает поиск с ранговыми корреляциями
+// с полным перебором
+func TestFindRegionsRankCorrelation(t *testing.T) {
+	rng := rand.New(rand.NewSource(21))
+	for _, name := range []string{CorrelationSpearman, CorrelationKendall} {
+		corr, err := CorrelationFunc(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for trial := range 4 {
+			rows, cols := 3+rng.Intn(4), 3+rng.Intn(4)
+			tables := make([]*mat.Dense, 3)
+			for a := range tables {
+				A, _ := randomPair(rng, rows, cols, 0.1*float64(trial%2))
+				tables[a] = A
+			}
+			for _, objective := range []string{ObjectiveMax, ObjectiveDet} {
+				want, _ := bruteRegion(tables, objective, corr, 2)
+				got, err := FindRegions(tables, objective, RegionOptions{MinSize: 2, Workers: 2, Corr: corr})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if g := got[0]; !g.same(want) || math.Abs(g.Score-want.Score) > 1e-9 {
+					t.Errorf("%s, %s, испытание %d: [%d:%d, %d:%d] %.12f, полный перебор [%d:%d, %d:%d] %.12f",
+						name, objective, trial, g.R1, g.R2, g.C1, g.C2, g.Score, want.R1, want.R2, want.C1, want.C2, want.Score)
+				}
+				c, _, _ := corr(tables[0], tables[2], got[0].R1, got[0].C1, got[0].R2, got[0].C2)
+				if trial%2 == 0 && math.Abs(got[0].Corr.At(0, 2)-c) > 1e-12 {
+					t.Errorf("%s: r(0,2) = %v, ожидалось %v", name, got[0].Corr.At(0, 2), c)
+				}
+			}
+
+			// Критерий pair совпадает с FindRegion по точкам, где заданы все таблицы
+			pair, err := FindRegions(tables, ObjectivePair, RegionOptions{MinSize: 2, Corr: corr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			masked := jointMask(tables)
+			single, err := FindRegion(masked[0], masked[1], RegionOptions{MinSize: 2, Corr: corr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pair[0].same(single) {
+				t.Errorf("%s: pair %+v, FindRegion %+v", name, pair[0], single)
+			}
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package statistics
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Этапы поиска области, передаваемые в RegionOptions.Progress
+const (
+	StageFull   = "full"   // полный перебор
+	StageCoarse = "coarse" // перебор на грубой сетке
+	StageRefine = "refine" // уточнение границ лучших областей грубой сетки
+)
+
+// defaultRefine - число уточняемых кандидатов грубого поиска по умолчанию
+const defaultRefine = 4
+
+// scoreTol - разница критериев, при которой области считаются равноценными
+// и выбирается область большей площади
+const scoreTol = 1e-10
+
+// RegionOptions - параметры поиска прямоугольной области
+type RegionOptions struct {
+	// MinSize - минимальная сторона области и минимальное число точек без пропусков
+	MinSize int
+	// Workers - число потоков (<= 0 - по числу CPU)
+	Workers int
+	// Step - шаг грубой сетки. При Step > 1 сначала перебираются области,
+	// границы которых лежат на сетке с шагом Step, затем границы Refine
+	// лучших из них уточняются перебором в окрестности ±Step.
+	// 0 или 1 - полный перебор всех областей
+	Step int
+	// Refine - число уточняемых кандидатов грубого поиска (0 - 4)
+	Refine int
+	// Top - число возвращаемых лучших областей (0 - 1)
+	Top int
+	// Corr - коэффициент корреляции областей (см. CorrelationFunc). nil -
+	// корреляция Пирсона по префиксным суммам за O(1) на область; иначе
+	// корреляция считается заново для каждой области и пары таблиц
+	Corr CorrFunc
+	// Progress вызывается после перебора областей с очередной начальной
+	// строкой; вызовы не пересекаются (nil - без отчета)
+	Progress func(stage string, done, total int)
+}
+
+// Region - прямоугольная область [R1:R2, C1:C2], границы включительно
+type Region struct {
+	R1, C1, R2, C2 int
+	Area           int     // число точек без пропусков
+	Score          float64 // значение критерия, меньше - лучше
+	// Corr - корреляционная матрица всех таблиц в области
+	// (заполняется FindRegions, nil в FindRegion)
+	Corr *mat.SymDense
+	// Cond - число обусловленности нормированной матрицы системы в области
+	// (заполняется FindRegions)
+	Cond float64
+}
+
+// same проверяет, что области совпадают по границам
+func (r Region) same(o Region) bool {
+	return r.R1 == o.R1 && r.C1 == o.C1 && r.R2 == o.R2 && r.C2 == o.C2
+}
+
+// better сравнивает области: сначала меньший критерий, затем большая площадь,
+// затем более ранняя в порядке перебора (R1, C1, R2, C2), как в полном переборе
+func (r Region) better(o Region) bool {
+	if d := r.Score - o.Score; math.Abs(d) > scoreTol {
+		return d < 0
+	}
+	if r.Area != o.Area {
+		return r.Area > o.Area
+	}
+	a, b := [4]int{r.R1, r.C1, r.R2, r.C2}, [4]int{o.R1, o.C1, o.R2, o.C2}
+	for k := range a {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return false
+}
+
+// regionObjective оценивает область; ok = false, если область не подходит
+type regionObjective func(r1, c1, r2, c2 int) (score float64, area int, ok bool)
+
+// objectiveFactory создает критерий для одного потока: критерий может
+// использовать собственные буферы без синхронизации
+type objectiveFactory func() regionObjective
+
+// FindRegion находит область с минимальным |corr(A, B)|, а среди равных -
+// с максимальным числом точек без пропусков. Статистики областей считаются
+// за O(1) по префиксным суммам, перебор распределяется по потокам.
+// Результат полного перебора совпадает с FindMaxAreaMinCorrelation
+func FindRegion(A, B *mat.Dense, opts RegionOptions) (Region, error) {
+	regions, err := findRegions(pairObjective(A, B, opts.Corr), A, opts)
+	if err != nil {
+		return Region{}, err
+	}
+	return regions[0], nil
+}
+
+// pairObjective - критерий |corr(A, B)| по префиксным суммам или, если
+// задана функция corr, по ее значению в каждой области
+func pairObjective(A, B *mat.Dense, corr CorrFunc) objectiveFactory {
+	if corr != nil {
+		return func() regionObjective {
+			return func(r1, c1, r2, c2 int) (float64, int, bool) {
+				c, n, err := corr(A, B, r1, c1, r2, c2)
+				return math.Abs(c), n, err == nil
+			}
+		}
+	}
+	sums := newPairSums(A, B)
+	return func() regionObjective {
+		return func(r1, c1, r2, c2 int) (float64, int, bool) {
+			corr, n, ok := sums.corr(r1, c1, r2, c2)
+			return math.Abs(corr), n, ok
+		}
+	}
+}
+
+// findRegions возвращает до opts.Top лучших областей матрицы размера shape
+func findRegions(newObj objectiveFactory, shape mat.Matrix, opts RegionOptions) ([]Region, error) {
+	rows, cols := shape.Dims()
+	minSize := max(opts.MinSize, 1)
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	top := max(opts.Top, 1)
+
+	if opts.Step <= 1 {
+		all := func(n int) []int { return window(0, n-1) }
+		p := newProgress(opts.Progress, StageFull, rows)
+		found := searchGrid(newObj, minSize, all(rows), all(rows), all(cols), all(cols), top, workers, p)
+		if len(found) == 0 {
+			return nil, fmt.Errorf("не найдено подходящих областей")
+		}
+		return found, nil
+	}
+
+	step := opts.Step
+	refine := opts.Refine
+	if refine <= 0 {
+		refine = defaultRefine
+	}
+	starts := func(n int) []int {
+		var s []int
+		for v := 0; v < n; v += step {
+			s = append(s, v)
+		}
+		return s
+	}
+	ends := func(n int) []int {
+		var e []int
+		for v := step - 1; v < n-1; v += step {
+			e = append(e, v)
+		}
+		return append(e, n-1)
+	}
+	rowStarts := starts(rows)
+	p := newProgress(opts.Progress, StageCoarse, len(rowStarts))
+	candidates := searchGrid(newObj, minSize, rowStarts, ends(rows), starts(cols), ends(cols), max(refine, top), workers, p)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("не найдено подходящих областей на сетке с шагом %d", step)
+	}
+
+	// Уточнение: каждая граница кандидата сдвигается не более чем на step
+	near := func(v, n int) []int { return window(max(v-step, 0), min(v+step, n-1)) }
+	refined := candidates[:min(refine, len(candidates))]
+	total := 0
+	for _, c := range refined {
+		total += len(near(c.R1, rows))
+	}
+	p = newProgress(opts.Progress, StageRefine, total)
+	var best []Region
+	for _, c := range candidates {
+		best = insertRegion(best, c, top)
+	}
+	for _, c := range refined {
+		found := searchGrid(newObj, minSize, near(c.R1, rows), near(c.R2, rows), near(c.C1, cols), near(c.C2, cols), top, workers, p)
+		for _, r := range found {
+			best = insertRegion(best, r, top)
+		}
+	}
+	return best, nil
+}
+
+// window возвращает возрастающую последовательность lo..hi
+func window(lo, hi int) []int {
+	w := make([]int, 0, max(hi-lo+1, 0))
+	for v := lo; v <= hi; v++ {
+		w = append(w, v)
+	}
+	return w
+}
+
+// searchGrid перебирает области с границами из возрастающих списков r1s, r2s,
+// c1s, c2s и возвращает до top лучших областей, от лучшей к худшей.
+// Каждая начальная строка r1 обрабатывается отдельным заданием
+func searchGrid(newObj objectiveFactory, minSize int, r1s, r2s, c1s, c2s []int, top, workers int, p *progress) []Region {
+	results := make([][]Region, len(r1s))
+	parallelFor(len(r1s), workers, func(k int) {
+		r1 := r1s[k]
+		obj := newObj()
+		var best []Region
+		for _, r2 := range r2s[sort.SearchInts(r2s, r1+minSize-1):] {
+			for _, c1 := range c1s {
+				for _, c2 := range c2s[sort.SearchInts(c2s, c1+minSize-1):] {
+					score, area, ok := obj(r1, c1, r2, c2)
+					if !ok || area < minSize {
+						continue
+					}
+					best = insertRegion(best, Region{R1: r1, C1: c1, R2: r2, C2: c2, Area: area, Score: score}, top)
+				}
+			}
+		}
+		results[k] = best
+		p.step()
+	})
+
+	var merged []Region
+	for _, res := range results {
+		for _, r := range res {
+			merged = insertRegion(merged, r, top)
+		}
+	}
+	return merged
+}
+
+// insertRegion вставляет r в упорядоченный список лучших областей длины не более top.
+// Уже присутствующая в списке область не добавляется повторно
+func insertRegion(best []Region, r Region, top int) []Region {
+	if len(best) == top && !r.better(best[len(best)-1]) {
+		return best
+	}
+	for _, b := range best {
+		if b.same(r) {
+			return best
+		}
+	}
+	k := sort.Search(len(best), func(i int) bool { return r.better(best[i]) })
+	if len(best) < top {
+		best = append(best, Region{})
+	}
+	copy(best[k+1:], best[k:])
+	best[k] = r
+	return best
+}
+
+// progress считает обработанные задания этапа и сообщает о них
+type progress struct {
+	mu    sync.Mutex
+	fn    func(stage string, done, total int)
+	stage string
+	done  int
+	total int
+}
+
+func newProgress(fn func(stage string, done, total int), stage string, total int) *progress {
+	return &progress{fn: fn, stage: stage, total: total}
+}
+
+func (p *progress) step() {
+	if p.fn == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.fn(p.stage, p.done, p.total)
+}
+
+// pairSums - префиксные суммы для корреляции двух матриц по парам без NaN.
+// Элемент (i, j) содержит сумму по прямоугольнику [0, i) x [0, j)
+type pairSums struct {
+	rows, cols          int
+	n, a, b, aa, bb, ab []float64
+}
+
+func newPairSums(A, B *mat.Dense) *pairSums {
+	rows, cols := A.Dims()
+	s := &pairSums{rows: rows, cols: cols}
+	size := (rows + 1) * (cols + 1)
+	s.n, s.a, s.b = make([]float64, size), make([]float64, size), make([]float64, size)
+	s.aa, s.bb, s.ab = make([]float64, size), make([]float64, size), make([]float64, size)
+
+	// Корреляция не зависит от сдвига, а вычитание средних уменьшает
+	// потерю точности в суммах квадратов
+	var meanA, meanB, count float64
+	for i := range rows {
+		for j := range cols {
+			a, b := A.At(i, j), B.At(i, j)
+			if !math.IsNaN(a) && !math.IsNaN(b) {
+				meanA, meanB, count = meanA+a, meanB+b, count+1
+			}
+		}
+	}
+	if count > 0 {
+		meanA, meanB = meanA/count, meanB/count
+	}
+
+	w := cols + 1
+	for i := range rows {
+		for j := range cols {
+			var n, a, b float64
+			if x, y := A.At(i, j), B.At(i, j); !math.IsNaN(x) && !math.IsNaN(y) {
+				n, a, b = 1, x-meanA, y-meanB
+			}
+			k := (i+1)*w + j + 1
+			add := func(p []float64, v float64) {
+				p[k] = v + p[k-1] + p[k-w] - p[k-w-1]
+			}
+			add(s.n, n)
+			add(s.a, a)
+			add(s.b, b)
+			add(s.aa, a*a)
+			add(s.bb, b*b)
+			add(s.ab, a*b)
+		}
+	}
+	return s
+}
+
+// sum возвращает сумму префиксного массива p по области [r1:r2, c1:c2]
+func (s *pairSums) sum(p []float64, r1, c1, r2, c2 int) float64 {
+	w := s.cols + 1
+	return p[(r2+1)*w+c2+1] - p[r1*w+c2+1] - p[(r2+1)*w+c1] + p[r1*w+c1]
+}
+
+// corr вычисляет корреляцию в области так же, как corr2SubmatrixCount:
+// при нулевой дисперсии обеих матриц корреляция равна 1, одной - 0
+func (s *pairSums) corr(r1, c1, r2, c2 int) (float64, int, bool) {
+	n := s.sum(s.n, r1, c1, r2, c2)
+	if n <= 1 {
+		return 0, int(n), false
+	}
+	meanA, meanB := s.sum(s.a, r1, c1, r2, c2)/n, s.sum(s.b, r1, c1, r2, c2)/n
+	aa, bb := s.sum(s.aa, r1, c1, r2, c2)/n, s.sum(s.bb, r1, c1, r2, c2)/n
+	varA, varB := aa-meanA*meanA, bb-meanB*meanB
+	// Остаток вычитания на уровне округления считается нулевой дисперсией
+	zeroA, zeroB := varA <= 1e-12*aa, varB <= 1e-12*bb
+	if zeroA || zeroB {
+		if zeroA && zeroB {
+			return 1, int(n), true
+		}
+		return 0, int(n), true
+	}
+	cov := s.sum(s.ab, r1, c1, r2, c2)/n - meanA*meanB
+	return cov / math.Sqrt(varA*varB), int(n), true
+}
@@ -0,0 +1,497 @@
+package statistics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Критерии выбора области по корреляциям таблиц долей
+const (
+	ObjectivePair = "pair" // |r| первых двух таблиц (как FindRegion)
+	ObjectiveMax  = "max"  // максимум |r_ij| по всем парам таблиц
+	ObjectiveMean = "mean" // среднее |r_ij| по всем парам таблиц
+	ObjectiveDet  = "det"  // 1 - det R, R - корреляционная матрица всех таблиц
+	ObjectiveCond = "cond" // число обусловленности сбалансированной матрицы системы
+)
+
+// condMax - предел числа обусловленности: вырожденные области получают
+// это значение и сравниваются между собой по площади
+const condMax = 1e12
+
+// Objectives перечисляет все критерии выбора области
+var Objectives = []string{ObjectivePair, ObjectiveMax, ObjectiveMean, ObjectiveDet, ObjectiveCond}
+
+// ValidateObjective проверяет имя критерия
+func ValidateObjective(objective string) error {
+	for _, o := range Objectives {
+		if o == objective {
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестный критерий выбора области %q, допустимые: %s", objective, strings.Join(Objectives, ", "))
+}
+
+// FindRegions находит до opts.Top лучших областей по критерию objective
+// (см. Objectives). Точка учитывается, только если она задана (не NaN)
+// во всех таблицах; для каждой найденной области заполняются Corr и Cond.
+// Критерии, кроме cond, используют коэффициент корреляции opts.Corr
+func FindRegions(tables []*mat.Dense, objective string, opts RegionOptions) ([]Region, error) {
+	if len(tables) < 2 {
+		return nil, fmt.Errorf("для поиска области нужно хотя бы две таблицы, задано %d", len(tables))
+	}
+	if err := ValidateObjective(objective); err != nil {
+		return nil, err
+	}
+	sums := newMultiSums(tables)
+	gram := condObjective(newGramSums(tables))
+	// Все критерии считаются по точкам, где заданы все таблицы, иначе pair
+	// учитывал бы точки с пропусками в остальных таблицах
+	masked := jointMask(tables)
+	var newObj objectiveFactory
+	switch {
+	case objective == ObjectivePair:
+		newObj = pairObjective(masked[0], masked[1], opts.Corr)
+	case objective == ObjectiveCond:
+		newObj = gram
+	case opts.Corr != nil:
+		newObj = corrFuncObjective(masked, opts.Corr, objective)
+	default:
+		newObj = multiObjective(sums, objective)
+	}
+
+	regions, err := findRegions(newObj, tables[0], opts)
+	if err != nil {
+		return nil, err
+	}
+	cond := gram()
+	for i := range regions {
+		r := &regions[i]
+		r.Cond, _, _ = cond(r.R1, r.C1, r.R2, r.C2)
+		buf := make([]float64, sums.k*sums.k)
+		if opts.Corr != nil {
+			corrMatrix(masked, opts.Corr, r.R1, r.C1, r.R2, r.C2, buf)
+		} else {
+			sums.corr(r.R1, r.C1, r.R2, r.C2, buf, make([]float64, sums.k))
+		}
+		r.Corr = mat.NewSymDense(sums.k, buf)
+	}
+	return regions, nil
+}
+
+// multiObjective - критерий по корреляционной матрице всех таблиц
+func multiObjective(sums *multiSums, objective string) objectiveFactory {
+	k := sums.k
+	return func() regionObjective {
+		corr := make([]float64, k*k)
+		work := make([]float64, k*k)
+		means := make([]float64, k)
+		return func(r1, c1, r2, c2 int) (float64, int, bool) {
+			n, ok := sums.corr(r1, c1, r2, c2, corr, means)
+			if !ok {
+				return 0, n, false
+			}
+			return corrScore(objective, corr, work, k), n, true
+		}
+	}
+}
+
+// corrFuncObjective - критерий по корреляционной матрице, элементы которой
+// считаются функцией corr в каждой области; tables замаскированы jointMask
+func corrFuncObjective(tables []*mat.Dense, corr CorrFunc, objective string) objectiveFactory {
+	k := len(tables)
+	return func() regionObjective {
+		c := make([]float64, k*k)
+		work := make([]float64, k*k)
+		return func(r1, c1, r2, c2 int) (float64, int, bool) {
+			n, ok := corrMatrix(tables, corr, r1, c1, r2, c2, c)
+			if !ok {
+				return 0, n, false
+			}
+			return corrScore(objective, c, work, k), n, true
+		}
+	}
+}
+
+// corrMatrix записывает в out (k x k по строкам) корреляции всех пар таблиц
+// в области и возвращает число учтенных точек
+func corrMatrix(tables []*mat.Dense, corr CorrFunc, r1, c1, r2, c2 int, out []float64) (int, bool) {
+	k := len(tables)
+	n := 0
+	for a := range k {
+		out[a*k+a] = 1
+		for b := a + 1; b < k; b++ {
+			r, count, err := corr(tables[a], tables[b], r1, c1, r2, c2)
+			if err != nil {
+				return count, false
+			}
+			out[a*k+b], out[b*k+a], n = r, r, count
+		}
+	}
+	return n, true
+}
+
+// corrScore вычисляет критерий objective по корреляционной матрице corr
+// (k x k по строкам); work - буфер того же размера
+func corrScore(objective string, corr, work []float64, k int) float64 {
+	switch objective {
+	case ObjectiveMax, ObjectiveMean:
+		worst, sum := 0.0, 0.0
+		for i := range k {
+			for j := i + 1; j < k; j++ {
+				r := math.Abs(corr[i*k+j])
+				worst, sum = math.Max(worst, r), sum+r
+			}
+		}
+		if objective == ObjectiveMax {
+			return worst
+		}
+		return sum / float64(k*(k-1)/2)
+	case ObjectiveDet:
+		copy(work, corr)
+		return 1 - det(work, k)
+	}
+	panic("неизвестный критерий выбора области: " + objective)
+}
+
+// jointMask возвращает копии таблиц, в которых точка заменена на NaN,
+// если она не задана хотя бы в одной таблице
+func jointMask(tables []*mat.Dense) []*mat.Dense {
+	rows, cols := tables[0].Dims()
+	masked := make([]*mat.Dense, len(tables))
+	for a, t := range tables {
+		masked[a] = mat.DenseCopyOf(t)
+	}
+	for i := range rows {
+		for j := range cols {
+			for _, t := range tables {
+				if math.IsNaN(t.At(i, j)) {
+					for _, m := range masked {
+						m.Set(i, j, math.NaN())
+					}
+					break
+				}
+			}
+		}
+	}
+	return masked
+}
+
+// det вычисляет определитель матрицы k x k (по строкам) методом Гаусса
+// с выбором главного элемента; содержимое a разрушается
+func det(a []float64, k int) float64 {
+	d := 1.0
+	for col := range k {
+		pivot := col
+		for i := col + 1; i < k; i++ {
+			if math.Abs(a[i*k+col]) > math.Abs(a[pivot*k+col]) {
+				pivot = i
+			}
+		}
+		if a[pivot*k+col] == 0 {
+			return 0
+		}
+		if pivot != col {
+			for j := range k {
+				a[col*k+j], a[pivot*k+j] = a[pivot*k+j], a[col*k+j]
+			}
+			d = -d
+		}
+		d *= a[col*k+col]
+		for i := col + 1; i < k; i++ {
+			f := a[i*k+col] / a[col*k+col]
+			for j := col; j < k; j++ {
+				a[i*k+j] -= f * a[col*k+j]
+			}
+		}
+	}
+	return d
+}
+
+// multiSums - префиксные суммы первых и вторых моментов k таблиц по точкам,
+// заданным во всех таблицах. Элемент (i, j) префиксного массива содержит
+// сумму по прямоугольнику [0, i) x [0, j)
+type multiSums struct {
+	rows, cols, k int
+	n             []float64
+	s             [][]float64 // s[a] - суммы таблицы a
+	q             [][]float64 // q[a*k+b], a <= b - суммы произведений таблиц a и b
+}
+
+func newMultiSums(tables []*mat.Dense) *multiSums {
+	rows, cols := tables[0].Dims()
+	k := len(tables)
+	m := &multiSums{rows: rows, cols: cols, k: k, s: make([][]float64, k), q: make([][]float64, k*k)}
+	size := (rows + 1) * (cols + 1)
+	m.n = make([]float64, size)
+	for a := range k {
+		m.s[a] = make([]float64, size)
+		for b := a; b < k; b++ {
+			m.q[a*k+b] = make([]float64, size)
+		}
+	}
+
+	// Как и в pairSums, суммы считаются по отклонениям от средних
+	valid := func(i, j int) bool {
+		for _, t := range tables {
+			if math.IsNaN(t.At(i, j)) {
+				return false
+			}
+		}
+		return true
+	}
+	means := make([]float64, k)
+	count := 0.0
+	for i := range rows {
+		for j := range cols {
+			if valid(i, j) {
+				for a, t := range tables {
+					means[a] += t.At(i, j)
+				}
+				count++
+			}
+		}
+	}
+	for a := range means {
+		means[a] /= max(count, 1)
+	}
+
+	w := cols + 1
+	x := make([]float64, k)
+	for i := range rows {
+		for j := range cols {
+			n := 0.0
+			for a := range x {
+				x[a] = 0
+			}
+			if valid(i, j) {
+				n = 1
+				for a, t := range tables {
+					x[a] = t.At(i, j) - means[a]
+				}
+			}
+			c := (i+1)*w + j + 1
+			add := func(p []float64, v float64) {
+				p[c] = v + p[c-1] + p[c-w] - p[c-w-1]
+			}
+			add(m.n, n)
+			for a := range k {
+				add(m.s[a], x[a])
+				for b := a; b < k; b++ {
+					add(m.q[a*k+b], x[a]*x[b])
+				}
+			}
+		}
+	}
+	return m
+}
+
+func (m *multiSums) sum(p []float64, r1, c1, r2, c2 int) float64 {
+	w := m.cols + 1
+	return p[(r2+1)*w+c2+1] - p[r1*w+c2+1] - p[(r2+1)*w+c1] + p[r1*w+c1]
+}
+
+// corr записывает в out (k x k по строкам) корреляционную матрицу таблиц в
+// области, means - буфер длины k. Корреляции пар с нулевой дисперсией
+// определяются как в corr2SubmatrixCount: 1, если постоянны обе таблицы, иначе 0
+func (m *multiSums) corr(r1, c1, r2, c2 int, out, means []float64) (int, bool) {
+	k := m.k
+	n := m.sum(m.n, r1, c1, r2, c2)
+	if n <= 1 {
+		return int(n), false
+	}
+	// Дисперсии временно хранятся на диагонали out
+	for a := range k {
+		means[a] = m.sum(m.s[a], r1, c1, r2, c2) / n
+		sq := m.sum(m.q[a*k+a], r1, c1, r2, c2) / n
+		v := sq - means[a]*means[a]
+		if v <= 1e-12*sq {
+			v = 0
+		}
+		out[a*k+a] = v
+	}
+	for a := range k {
+		for b := a + 1; b < k; b++ {
+			va, vb := out[a*k+a], out[b*k+b]
+			var r float64
+			switch {
+			case va == 0 && vb == 0:
+				r = 1
+			case va == 0 || vb == 0:
+				r = 0
+			default:
+				cov := m.sum(m.q[a*k+b], r1, c1, r2, c2)/n - means[a]*means[b]
+				r = cov / math.Sqrt(va*vb)
+			}
+			out[a*k+b], out[b*k+a] = r, r
+		}
+	}
+	for a := range k {
+		out[a*k+a] = 1
+	}
+	return int(n), true
+}
+
+// PrintRegions выводит найденные области с числом обусловленности и
+// корреляциями всех пар таблиц
+func PrintRegions(regions []Region, objective string, names []string) {
+	fmt.Printf("=== Области-кандидаты (критерий %s) ===\n", objective)
+	fmt.Printf("%3s  %-20s  %6s  %10s  %10s", "#", "область", "точек", "критерий", "cond")
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			fmt.Printf("  %10s", "r("+names[i]+","+names[j]+")")
+		}
+	}
+	fmt.Println()
+	for k, r := range regions {
+		bounds := fmt.Sprintf("[%d:%d, %d:%d]", r.R1, r.R2, r.C1, r.C2)
+		fmt.Printf("%3d  %-20s  %6d  %10.4f  %10.4g", k+1, bounds, r.Area, r.Score, r.Cond)
+		for i := range names {
+			for j := i + 1; j < len(names); j++ {
+				fmt.Printf("  %+10.4f", r.Corr.At(i, j))
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// condObjective - число обусловленности матрицы системы в области: строки
+// [n_1 ... n_k] нормируются на единичную длину, как в solver.BalanceProblem,
+// и cond = sqrt(λmax / λmin) для матрицы Грама G = Σ a_i a_i^T
+func condObjective(sums *gramSums) objectiveFactory {
+	k := sums.k
+	return func() regionObjective {
+		g := make([]float64, k*k)
+		return func(r1, c1, r2, c2 int) (float64, int, bool) {
+			n := sums.gram(r1, c1, r2, c2, g)
+			if n < k {
+				return 0, n, false
+			}
+			lmin, lmax := symEigenRange(g, k)
+			if lmax <= 0 || lmin <= lmax/(condMax*condMax) {
+				return condMax, n, true
+			}
+			return math.Sqrt(lmax / lmin), n, true
+		}
+	}
+}
+
+// symEigenRange возвращает наименьшее и наибольшее собственные значения
+// симметричной матрицы k x k (по строкам) методом вращений Якоби;
+// содержимое a разрушается
+func symEigenRange(a []float64, k int) (float64, float64) {
+	for sweep := 0; sweep < 50; sweep++ {
+		off, diag := 0.0, 0.0
+		for i := range k {
+			diag += a[i*k+i] * a[i*k+i]
+			for j := i + 1; j < k; j++ {
+				off += a[i*k+j] * a[i*k+j]
+			}
+		}
+		if off <= 1e-30*diag {
+			break
+		}
+		for p := range k {
+			for q := p + 1; q < k; q++ {
+				apq := a[p*k+q]
+				if apq == 0 {
+					continue
+				}
+				theta := (a[q*k+q] - a[p*k+p]) / (2 * apq)
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for r := range k {
+					arp, arq := a[r*k+p], a[r*k+q]
+					a[r*k+p], a[r*k+q] = c*arp-s*arq, s*arp+c*arq
+				}
+				for r := range k {
+					apr, aqr := a[p*k+r], a[q*k+r]
+					a[p*k+r], a[q*k+r] = c*apr-s*aqr, s*apr+c*aqr
+				}
+			}
+		}
+	}
+	lmin, lmax := a[0], a[0]
+	for i := 1; i < k; i++ {
+		lmin, lmax = math.Min(lmin, a[i*k+i]), math.Max(lmax, a[i*k+i])
+	}
+	return lmin, lmax
+}
+
+// gramSums - префиксные суммы матрицы Грама строк [n_1 ... n_k], нормированных
+// на единичную длину, по точкам, заданным во всех таблицах. Средние не
+// вычитаются: система решается без свободного члена
+type gramSums struct {
+	rows, cols, k int
+	n             []float64
+	g             [][]float64 // g[a*k+b], a <= b - суммы произведений компонент a и b
+}
+
+func newGramSums(tables []*mat.Dense) *gramSums {
+	rows, cols := tables[0].Dims()
+	k := len(tables)
+	m := &gramSums{rows: rows, cols: cols, k: k, g: make([][]float64, k*k)}
+	size := (rows + 1) * (cols + 1)
+	m.n = make([]float64, size)
+	for a := range k {
+		for b := a; b < k; b++ {
+			m.g[a*k+b] = make([]float64, size)
+		}
+	}
+
+	w := cols + 1
+	x := make([]float64, k)
+	for i := range rows {
+		for j := range cols {
+			n, norm := 1.0, 0.0
+			for a, t := range tables {
+				x[a] = t.At(i, j)
+				if math.IsNaN(x[a]) {
+					n = 0
+				}
+				norm += x[a] * x[a]
+			}
+			// Нулевая строка не меняет систему, как и в solver.BalanceProblem
+			norm = math.Sqrt(norm)
+			for a := range x {
+				if n == 0 || norm <= 1e-12 {
+					x[a] = 0
+				} else {
+					x[a] /= norm
+				}
+			}
+			c := (i+1)*w + j + 1
+			add := func(p []float64, v float64) {
+				p[c] = v + p[c-1] + p[c-w] - p[c-w-1]
+			}
+			add(m.n, n)
+			for a := range k {
+				for b := a; b < k; b++ {
+					add(m.g[a*k+b], x[a]*x[b])
+				}
+			}
+		}
+	}
+	return m
+}
+
+func (m *gramSums) sum(p []float64, r1, c1, r2, c2 int) float64 {
+	w := m.cols + 1
+	return p[(r2+1)*w+c2+1] - p[r1*w+c2+1] - p[(r2+1)*w+c1] + p[r1*w+c1]
+}
+
+// gram записывает в out (k x k по строкам) матрицу Грама области и
+// возвращает число точек
+func (m *gramSums) gram(r1, c1, r2, c2 int, out []float64) int {
+	k := m.k
+	for a := range k {
+		for b := a; b < k; b++ {
+			v := m.sum(m.g[a*k+b], r1, c1, r2, c2)
+			out[a*k+b], out[b*k+a] = v, v
+		}
+	}
+	return int(math.Round(m.sum(m.n, r1, c1, r2, c2)))
+}
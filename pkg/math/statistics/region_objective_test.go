@@ -0,0 +1,341 @@
+package statistics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// bruteRegion перебирает все области и считает корреляции каждой пары
+// заново функцией corr по точкам, заданным во всех таблицах
+func bruteRegion(tables []*mat.Dense, objective string, corr CorrFunc, minSize int) (Region, bool) {
+	rows, cols := tables[0].Dims()
+	k := len(tables)
+	masked := make([]*mat.Dense, k)
+	for a, t := range tables {
+		masked[a] = mat.DenseCopyOf(t)
+		for i := range rows {
+			for j := range cols {
+				for _, u := range tables {
+					if math.IsNaN(u.At(i, j)) {
+						masked[a].Set(i, j, math.NaN())
+					}
+				}
+			}
+		}
+	}
+
+	var best Region
+	found := false
+	for r1 := range rows {
+		for c1 := range cols {
+			for r2 := r1 + minSize - 1; r2 < rows; r2++ {
+				for c2 := c1 + minSize - 1; c2 < cols; c2++ {
+					R := make([]float64, k*k)
+					area, ok := 0, true
+					for a := range k {
+						R[a*k+a] = 1
+						for b := a + 1; b < k; b++ {
+							r, n, err := corr(masked[a], masked[b], r1, c1, r2, c2)
+							ok = ok && err == nil
+							R[a*k+b], R[b*k+a], area = r, r, n
+						}
+					}
+					if !ok || area < minSize {
+						continue
+					}
+					var score float64
+					switch objective {
+					case ObjectivePair:
+						score = math.Abs(R[1])
+					case ObjectiveMax:
+						for a := range k {
+							for b := a + 1; b < k; b++ {
+								score = math.Max(score, math.Abs(R[a*k+b]))
+							}
+						}
+					case ObjectiveMean:
+						for a := range k {
+							for b := a + 1; b < k; b++ {
+								score += math.Abs(R[a*k+b]) / float64(k*(k-1)/2)
+							}
+						}
+					case ObjectiveDet:
+						score = 1 - mat.Det(mat.NewDense(k, k, R))
+					}
+					r := Region{R1: r1, C1: c1, R2: r2, C2: c2, Area: area, Score: score}
+					if !found || r.better(best) {
+						best, found = r, true
+					}
+				}
+			}
+		}
+	}
+	return best, found
+}
+
+func TestFindRegionsMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	for trial := range 12 {
+		rows, cols := 3+rng.Intn(5), 3+rng.Intn(5)
+		minSize := 1 + trial%3
+		tables := make([]*mat.Dense, 3)
+		for a := range tables {
+			tables[a] = mat.NewDense(rows, cols, nil)
+		}
+		for i := range rows {
+			for j := range cols {
+				d, u := rng.Float64(), rng.Float64()
+				tables[0].Set(i, j, d)
+				tables[1].Set(i, j, u)
+				tables[2].Set(i, j, 0.7*d+0.3*u+0.2*rng.Float64())
+				if trial%2 == 1 && rng.Float64() < 0.1 {
+					tables[rng.Intn(3)].Set(i, j, math.NaN())
+				}
+			}
+		}
+
+		for _, objective := range []string{ObjectivePair, ObjectiveMax, ObjectiveMean, ObjectiveDet} {
+			want, ok := bruteRegion(tables, objective, corr2SubmatrixCount, minSize)
+			got, err := FindRegions(tables, objective, RegionOptions{MinSize: minSize, Workers: 2, Top: 3})
+			if !ok {
+				if err == nil {
+					t.Errorf("испытание %d, %s: ожидалась ошибка", trial, objective)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("испытание %d, %s: %v", trial, objective, err)
+			}
+			g := got[0]
+			if !g.same(want) || math.Abs(g.Score-want.Score) > 1e-9 || g.Area != want.Area {
+				t.Errorf("испытание %d, %s: [%d:%d, %d:%d] %.12f, полный перебор [%d:%d, %d:%d] %.12f",
+					trial, objective, g.R1, g.R2, g.C1, g.C2, g.Score, want.R1, want.R2, want.C1, want.C2, want.Score)
+			}
+			for k := 1; k < len(got); k++ {
+				if got[k].better(got[k-1]) || got[k].same(got[k-1]) {
+					t.Errorf("испытание %d, %s: кандидаты не упорядочены", trial, objective)
+				}
+			}
+		}
+	}
+}
+
+// TestFindRegionsPair проверяет, что критерий pair совпадает с FindRegion,
+// а корреляции кандидатов согласуются с Corr2Submatrix
+func TestFindRegionsPair(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	tables := make([]*mat.Dense, 3)
+	for a := range tables {
+		A, _ := randomPair(rng, 7, 6, 0)
+		tables[a] = A
+	}
+	want, err := FindRegion(tables[0], tables[1], RegionOptions{MinSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := FindRegions(tables, ObjectivePair, RegionOptions{MinSize: 2, Top: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 || !got[0].same(want) {
+		t.Fatalf("лучшая область %+v, ожидалась %+v", got[0], want)
+	}
+	for _, r := range got {
+		for a := range 3 {
+			for b := a + 1; b < 3; b++ {
+				c, err := Corr2Submatrix(tables[a], tables[b], r.R1, r.C1, r.R2, r.C2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if math.Abs(r.Corr.At(a, b)-c) > 1e-9 {
+					t.Errorf("r(%d,%d) в %+v: %.12f, ожидалось %.12f", a, b, r, r.Corr.At(a, b), c)
+				}
+			}
+		}
+	}
+
+	if _, err := FindRegions(tables, "corr", RegionOptions{}); err == nil {
+		t.Error("ожидалась ошибка для неизвестного критерия")
+	}
+}
+
+// bruteCond считает число обусловленности нормированной матрицы системы
+// в области через SVD
+func bruteCond(tables []*mat.Dense, r1, c1, r2, c2 int) (float64, int) {
+	k := len(tables)
+	var data []float64
+	for i := r1; i <= r2; i++ {
+		for j := c1; j <= c2; j++ {
+			row, norm := make([]float64, k), 0.0
+			for a, t := range tables {
+				row[a] = t.At(i, j)
+				norm += row[a] * row[a]
+			}
+			if math.IsNaN(norm) {
+				continue
+			}
+			if norm = math.Sqrt(norm); norm > 1e-12 {
+				for a := range row {
+					row[a] /= norm
+				}
+			}
+			data = append(data, row...)
+		}
+	}
+	n := len(data) / k
+	if n < k {
+		return 0, n
+	}
+	var svd mat.SVD
+	svd.Factorize(mat.NewDense(n, k, data), mat.SVDNone)
+	return math.Min(svd.Cond(), condMax), n
+}
+
+// TestFindRegionsCond сравнивает критерий cond с SVD во всех областях
+func TestFindRegionsCond(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for trial := range 8 {
+		rows, cols := 3+rng.Intn(4), 3+rng.Intn(4)
+		tables := make([]*mat.Dense, 3)
+		for a := range tables {
+			tables[a] = mat.NewDense(rows, cols, nil)
+		}
+		for i := range rows {
+			for j := range cols {
+				d, u := rng.Float64(), rng.Float64()
+				tables[0].Set(i, j, d)
+				tables[1].Set(i, j, u)
+				tables[2].Set(i, j, 1-0.5*(d+u)+0.1*rng.Float64())
+				if trial%2 == 1 && rng.Float64() < 0.1 {
+					tables[rng.Intn(3)].Set(i, j, math.NaN())
+				}
+			}
+		}
+
+		var want Region
+		found := false
+		for r1 := range rows {
+			for c1 := range cols {
+				for r2 := r1 + 1; r2 < rows; r2++ {
+					for c2 := c1 + 1; c2 < cols; c2++ {
+						score, n := bruteCond(tables, r1, c1, r2, c2)
+						if n < 3 {
+							continue
+						}
+						r := Region{R1: r1, C1: c1, R2: r2, C2: c2, Area: n, Score: score}
+						if !found || r.better(want) {
+							want, found = r, true
+						}
+					}
+				}
+			}
+		}
+		got, err := FindRegions(tables, ObjectiveCond, RegionOptions{MinSize: 2, Workers: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g := got[0]; !g.same(want) || math.Abs(g.Score-want.Score) > 1e-8*want.Score {
+			t.Errorf("испытание %d: [%d:%d, %d:%d] cond=%.10f, SVD [%d:%d, %d:%d] cond=%.10f",
+				trial, g.R1, g.R2, g.C1, g.C2, g.Score, want.R1, want.R2, want.C1, want.C2, want.Score)
+		}
+	}
+}
+
+// TestFindRegionsCondCollinear - сцена, где доли попарно некоррелированы,
+// но линейно зависимы (s = d + u): критерий pair не видит вырожденности,
+// а Cond найденной области равен condMax
+func TestFindRegionsCondCollinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	rows, cols := 8, 10
+	tables := make([]*mat.Dense, 3)
+	for a := range tables {
+		tables[a] = mat.NewDense(rows, cols, nil)
+	}
+	for i := range rows {
+		for j := range cols {
+			d, u := rng.Float64(), rng.Float64()
+			tables[0].Set(i, j, d)
+			tables[1].Set(i, j, u)
+			tables[2].Set(i, j, d+u)
+		}
+	}
+
+	pair, err := FindRegions(tables, ObjectivePair, RegionOptions{MinSize: 3, Top: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pair {
+		if p.Score > 0.5 || p.Cond != condMax {
+			t.Errorf("pair: область [%d:%d, %d:%d] |r|=%.3f cond=%.3g", p.R1, p.R2, p.C1, p.C2, p.Score, p.Cond)
+		}
+	}
+
+	// Нарушение зависимости в одной строке делает систему обусловленной
+	for j := range cols {
+		tables[2].Set(rows-1, j, rng.Float64())
+	}
+	cond, err := FindRegions(tables, ObjectiveCond, RegionOptions{MinSize: 3, Top: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cond {
+		want, _ := bruteCond(tables, c.R1, c.C1, c.R2, c.C2)
+		if c.R2 != rows-1 || c.Cond != c.Score || math.Abs(c.Cond-want) > 1e-8*want {
+			t.Errorf("cond: область [%d:%d, %d:%d] cond=%.10g, SVD %.10g", c.R1, c.R2, c.C1, c.C2, c.Cond, want)
+		}
+	}
+}
+
+// TestLocalCorrelation сравнивает локальные корреляции с Corr2Submatrix в окне
+func TestLocalCorrelation(t *testing.T) {
+	rng := rand.New(rand.NewSource(12))
+	rows, cols, radius := 6, 7, 1
+	tables := make([]*mat.Dense, 3)
+	for a := range tables {
+		A, _ := randomPair(rng, rows, cols, 0.1)
+		tables[a] = A
+	}
+	masked := make([]*mat.Dense, len(tables))
+	for a, tb := range tables {
+		masked[a] = mat.DenseCopyOf(tb)
+		for i := range rows {
+			for j := range cols {
+				for _, u := range tables {
+					if math.IsNaN(u.At(i, j)) {
+						masked[a].Set(i, j, math.NaN())
+					}
+				}
+			}
+		}
+	}
+
+	local := LocalCorrelation(tables, radius)
+	for i := range rows {
+		for j := range cols {
+			r1, c1 := max(i-radius, 0), max(j-radius, 0)
+			r2, c2 := min(i+radius, rows-1), min(j+radius, cols-1)
+			want, n := 0.0, 0
+			for a := range tables {
+				for b := a + 1; b < len(tables); b++ {
+					r, count, err := corr2SubmatrixCount(masked[a], masked[b], r1, c1, r2, c2)
+					if err == nil {
+						want, n = math.Max(want, math.Abs(r)), count
+					}
+				}
+			}
+			got := local.At(i, j)
+			if n < 3 {
+				if !math.IsNaN(got) {
+					t.Errorf("(%d, %d): %v при %d точках, ожидался NaN", i, j, got, n)
+				}
+				continue
+			}
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("(%d, %d): %.12f, ожидалось %.12f", i, j, got, want)
+			}
+		}
+	}
+}
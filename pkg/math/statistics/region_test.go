@@ -0,0 +1,127 @@
+package statistics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// randomPair возвращает две случайные матрицы с долей nanFrac пропусков
+func randomPair(rng *rand.Rand, rows, cols int, nanFrac float64) (*mat.Dense, *mat.Dense) {
+	A, B := mat.NewDense(rows, cols, nil), mat.NewDense(rows, cols, nil)
+	for i := range rows {
+		for j := range cols {
+			a := rng.Float64()
+			A.Set(i, j, a)
+			B.Set(i, j, 0.5*a+rng.Float64())
+			if rng.Float64() < nanFrac {
+				A.Set(i, j, math.NaN())
+			}
+		}
+	}
+	return A, B
+}
+
+// TestFindRegionMatchesBruteForce сравнивает поиск по префиксным суммам
+// с полным перебором FindMaxAreaMinCorrelation
+func TestFindRegionMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for trial := range 30 {
+		rows, cols := 3+rng.Intn(6), 3+rng.Intn(6)
+		minSize := 1 + trial%3
+		A, B := randomPair(rng, rows, cols, 0.15*float64(trial%2))
+
+		r1, c1, r2, c2, corr, wantErr := FindMaxAreaMinCorrelation(A, B, minSize)
+		got, err := FindRegion(A, B, RegionOptions{MinSize: minSize, Workers: 3})
+		if (err != nil) != (wantErr != nil) {
+			t.Fatalf("испытание %d: ошибка %v, полный перебор %v", trial, err, wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if got.R1 != r1 || got.C1 != c1 || got.R2 != r2 || got.C2 != c2 || math.Abs(got.Score-corr) > 1e-9 {
+			t.Errorf("испытание %d (%dx%d, minSize %d): [%d:%d, %d:%d] |r|=%.12f, полный перебор [%d:%d, %d:%d] |r|=%.12f",
+				trial, rows, cols, minSize, got.R1, got.R2, got.C1, got.C2, got.Score, r1, r2, c1, c2, corr)
+		}
+	}
+}
+
+// TestFindRegionCoarse проверяет грубый поиск с уточнением на сцене, где
+// доли некоррелированы только в одном блоке, и отчет о ходе поиска
+func TestFindRegionCoarse(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	rows, cols := 60, 80
+	A, B := mat.NewDense(rows, cols, nil), mat.NewDense(rows, cols, nil)
+	inBlock := func(i, j int) bool { return i >= 20 && i < 45 && j >= 30 && j < 70 }
+	for i := range rows {
+		for j := range cols {
+			a := rng.Float64()
+			A.Set(i, j, a)
+			if inBlock(i, j) {
+				B.Set(i, j, rng.Float64())
+			} else {
+				B.Set(i, j, a+0.01*rng.Float64())
+			}
+		}
+	}
+
+	full, err := FindRegion(A, B, RegionOptions{MinSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := map[string]int{}
+	last := map[string][2]int{}
+	coarse, err := FindRegion(A, B, RegionOptions{
+		MinSize: 5,
+		Step:    5,
+		Progress: func(stage string, done, total int) {
+			calls[stage]++
+			last[stage] = [2]int{done, total}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coarse.Score > full.Score+0.01 {
+		t.Errorf("|r| грубого поиска %.4f, полного %.4f", coarse.Score, full.Score)
+	}
+	// Край области может захватить строку или столбец коррелированного фона
+	inside := 0
+	for i := coarse.R1; i <= coarse.R2; i++ {
+		for j := coarse.C1; j <= coarse.C2; j++ {
+			if inBlock(i, j) {
+				inside++
+			}
+		}
+	}
+	if float64(inside) < 0.9*float64(coarse.Area) {
+		t.Errorf("область [%d:%d, %d:%d] выходит за некоррелированный блок", coarse.R1, coarse.R2, coarse.C1, coarse.C2)
+	}
+	for _, stage := range []string{StageCoarse, StageRefine} {
+		if l := last[stage]; calls[stage] == 0 || l[0] != l[1] || calls[stage] != l[1] {
+			t.Errorf("этап %s: %d вызовов, последний %v", stage, calls[stage], l)
+		}
+	}
+}
+
+func BenchmarkFindRegion(b *testing.B) {
+	A, B := randomPair(rand.New(rand.NewSource(1)), 100, 200, 0.05)
+	for _, bm := range []struct {
+		name string
+		opts RegionOptions
+	}{
+		{"full", RegionOptions{MinSize: 5}},
+		{"step10", RegionOptions{MinSize: 5, Step: 10}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for range b.N {
+				if _, err := FindRegion(A, B, bm.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package selection
+
+import "sort"
+
+// Components размечает 8-связные компоненты маски: точки слоя, смещающегося
+// по высоте на одну строку между соседними столбцами, остаются связными.
+// Возвращает метку компоненты каждой точки (-1 вне маски) и размеры компонент;
+// компоненты нумеруются в порядке обхода по строкам
+func Components(mask []bool, rows, cols int) ([]int, []int) {
+	labels := make([]int, len(mask))
+	for k := range labels {
+		labels[k] = -1
+	}
+	var sizes []int
+	var stack []int
+	for start, ok := range mask {
+		if !ok || labels[start] >= 0 {
+			continue
+		}
+		label := len(sizes)
+		sizes = append(sizes, 0)
+		labels[start] = label
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			k := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			sizes[label]++
+			i, j := k/cols, k%cols
+			for di := -1; di <= 1; di++ {
+				for dj := -1; dj <= 1; dj++ {
+					ni, nj := i+di, j+dj
+					if ni < 0 || ni >= rows || nj < 0 || nj >= cols {
+						continue
+					}
+					if n := ni*cols + nj; mask[n] && labels[n] < 0 {
+						labels[n] = label
+						stack = append(stack, n)
+					}
+				}
+			}
+		}
+	}
+	return labels, sizes
+}
+
+// LargestComponents оставляет в маске k наибольших компонент, содержащих не
+// менее minPoints точек (k <= 0 - все такие компоненты). Возвращает новую
+// маску и размеры оставленных компонент по убыванию
+func LargestComponents(mask []bool, rows, cols, k, minPoints int) ([]bool, []int) {
+	labels, sizes := Components(mask, rows, cols)
+	order := make([]int, 0, len(sizes))
+	for label, size := range sizes {
+		if size >= minPoints {
+			order = append(order, label)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sizes[order[a]] > sizes[order[b]] })
+	if k > 0 && len(order) > k {
+		order = order[:k]
+	}
+
+	keep := make([]bool, len(sizes))
+	kept := make([]int, len(order))
+	for n, label := range order {
+		keep[label] = true
+		kept[n] = sizes[label]
+	}
+	res := make([]bool, len(mask))
+	for p, label := range labels {
+		res[p] = label >= 0 && keep[label]
+	}
+	return res, kept
+}
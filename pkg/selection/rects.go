@@ -0,0 +1,60 @@
+package selection
+
+import (
+	"fmt"
+	"math"
+
+	"classification-project/pkg/math/statistics"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Rectangles жадно выбирает до k областей по критерию objective (см.
+// statistics.FindRegions) среди точек маски: после выбора очередной области
+// ее точки исключаются, поэтому области не имеют общих точек. Возвращает
+// области в порядке выбора и маску их объединения. Поиск прекращается
+// раньше, если подходящих областей не осталось
+func Rectangles(tables []*mat.Dense, mask []bool, k int, objective string, opts statistics.RegionOptions) ([]statistics.Region, []bool, error) {
+	rows, cols := tables[0].Dims()
+	if len(mask) != rows*cols {
+		return nil, nil, fmt.Errorf("размер маски (%d) не совпадает с размером таблиц %dx%d", len(mask), rows, cols)
+	}
+	left := make([]*mat.Dense, len(tables))
+	for a, t := range tables {
+		left[a] = mat.DenseCopyOf(t)
+		for i := range rows {
+			for j := range cols {
+				if !mask[i*cols+j] {
+					left[a].Set(i, j, math.NaN())
+				}
+			}
+		}
+	}
+
+	opts.Top = 1
+	union := make([]bool, len(mask))
+	var regions []statistics.Region
+	for len(regions) < max(k, 1) {
+		found, err := statistics.FindRegions(left, objective, opts)
+		if err != nil {
+			if len(regions) > 0 {
+				break
+			}
+			return nil, nil, err
+		}
+		r := found[0]
+		regions = append(regions, r)
+		for i := r.R1; i <= r.R2; i++ {
+			for j := r.C1; j <= r.C2; j++ {
+				// Область включает только точки, заданные во всех таблицах
+				ok := true
+				for _, t := range left {
+					ok = ok && !math.IsNaN(t.At(i, j))
+					t.Set(i, j, math.NaN())
+				}
+				union[i*cols+j] = union[i*cols+j] || ok
+			}
+		}
+	}
+	return regions, union, nil
+}
@@ -0,0 +1,154 @@
+// Package selection строит маску точек сцены, по которым составляется система:
+// точки отбираются по правилам (пороги долей, β, диапазон высот, локальная
+// декорреляция долей), затем из маски выделяются связные компоненты или
+// объединение нескольких непересекающихся прямоугольных областей
+package selection
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"classification-project/internal/models"
+	"classification-project/pkg/math/statistics"
+	"classification-project/pkg/solver"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Способы выбора точек
+const (
+	ModeRegion     = "region"     // одна прямоугольная область (statistics.FindRegions)
+	ModeComponents = "components" // связные компоненты маски правил
+	ModeRects      = "rects"      // объединение K лучших непересекающихся областей в маске правил
+)
+
+// Modes перечисляет все способы выбора точек
+var Modes = []string{ModeRegion, ModeComponents, ModeRects}
+
+// ValidateMode проверяет имя способа выбора точек
+func ValidateMode(mode string) error {
+	for _, m := range Modes {
+		if m == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестный способ выбора точек %q, допустимые: %s", mode, strings.Join(Modes, ", "))
+}
+
+// Config - способ и правила выбора точек
+type Config struct {
+	Mode  string // способ выбора (см. Modes)
+	K     int    // число компонент (0 - все) или областей (0 - одна)
+	Rules Rules
+}
+
+// Rules - правила отбора точек. Точка отбирается, если заданы все ее
+// входные значения (см. solver.ValidMask) и выполнены все правила
+type Rules struct {
+	MinFraction map[string]float64 // нижние пороги долей по именам классов
+	MaxFraction map[string]float64 // верхние пороги долей по именам классов
+	MinBeta     float64            // минимальное β (-Inf - без ограничения)
+	MinAltitude float64            // нижняя граница высоты по меткам строк (-Inf - без ограничения)
+	MaxAltitude float64            // верхняя граница высоты по меткам строк (+Inf - без ограничения)
+	// MinDecorrelation - минимальная локальная декорреляция 1 - max|r_ij| долей
+	// в окне радиуса Radius (0 - без ограничения)
+	MinDecorrelation float64
+	Radius           int
+}
+
+// DefaultRules возвращает правила, отбирающие все точки без пропусков
+func DefaultRules() Rules {
+	return Rules{MinBeta: math.Inf(-1), MinAltitude: math.Inf(-1), MaxAltitude: math.Inf(1), Radius: 2}
+}
+
+// Build возвращает маску (по строкам) точек, удовлетворяющих правилам.
+// Маска p.Selection не учитывается
+func Build(p models.InputParameters, rules Rules) ([]bool, error) {
+	if p.Classes == nil {
+		p.Classes = models.DefaultClasses
+	}
+	p.Selection = nil
+	rows, cols := p.N[0].Rows, p.N[0].Columns
+	mask := solver.ValidMask(p)
+
+	thresholds := func(limits map[string]float64, keep func(v, limit float64) bool) error {
+		for name, limit := range limits {
+			c, ok := p.Classes.ByName(name)
+			if !ok {
+				return fmt.Errorf("порог доли для неизвестного класса %q", name)
+			}
+			n := p.N[c.Column]
+			for k := range mask {
+				mask[k] = mask[k] && keep(n.Data[k], limit)
+			}
+		}
+		return nil
+	}
+	if err := thresholds(rules.MinFraction, func(v, limit float64) bool { return v >= limit }); err != nil {
+		return nil, err
+	}
+	if err := thresholds(rules.MaxFraction, func(v, limit float64) bool { return v <= limit }); err != nil {
+		return nil, err
+	}
+	for k := range mask {
+		mask[k] = mask[k] && p.Beta.Data[k] >= rules.MinBeta
+	}
+
+	if !math.IsInf(rules.MinAltitude, -1) || !math.IsInf(rules.MaxAltitude, 1) {
+		for i, label := range p.N[0].RowLabels {
+			h, err := strconv.ParseFloat(strings.TrimSpace(label), 64)
+			if err != nil {
+				return nil, fmt.Errorf("метка строки %q не является высотой: %w", label, err)
+			}
+			if h < rules.MinAltitude || h > rules.MaxAltitude {
+				for j := range cols {
+					mask[i*cols+j] = false
+				}
+			}
+		}
+	}
+
+	if rules.MinDecorrelation > 0 {
+		// Локальные корреляции считаются по всем точкам без пропусков,
+		// а не только по отобранным предыдущими правилами
+		valid := solver.ValidMask(p)
+		tables := make([]*mat.Dense, len(p.N))
+		for a, n := range p.N {
+			tables[a] = MaskedDense(n, valid)
+		}
+		local := statistics.LocalCorrelation(tables, rules.Radius)
+		for i := range rows {
+			for j := range cols {
+				// NaN не проходит сравнение и исключает точку
+				if !(1-local.At(i, j) >= rules.MinDecorrelation) {
+					mask[i*cols+j] = false
+				}
+			}
+		}
+	}
+	return mask, nil
+}
+
+// MaskedDense копирует таблицу в матрицу, заменяя точки вне маски на NaN
+func MaskedDense(t *models.Table, mask []bool) *mat.Dense {
+	data := append([]float64(nil), t.Data...)
+	for k, ok := range mask {
+		if !ok {
+			data[k] = math.NaN()
+		}
+	}
+	return mat.NewDense(t.Rows, t.Columns, data)
+}
+
+// Count возвращает число отобранных точек маски
+func Count(mask []bool) int {
+	n := 0
+	for _, ok := range mask {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,210 @@
+package selection
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"classification-project/internal/models"
+	"classification-project/pkg/math/statistics"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// scene возвращает параметры со случайными долями d, u, s, β и V на сетке
+// rows x cols; метки строк - высоты 1000, 1010, ...
+func scene(rng *rand.Rand, rows, cols int) models.InputParameters {
+	table := func(value func() float64) *models.Table {
+		colLabels, rowLabels := make([]string, cols), make([]string, rows)
+		for j := range colLabels {
+			colLabels[j] = fmt.Sprintf("T%d", j)
+		}
+		for i := range rowLabels {
+			rowLabels[i] = fmt.Sprint(1000 + 10*i)
+		}
+		data := make([]float64, rows*cols)
+		for k := range data {
+			data[k] = value()
+		}
+		return models.NewTable(rows, cols, data, colLabels, rowLabels)
+	}
+	p := models.InputParameters{Classes: models.DefaultClasses}
+	for range 3 {
+		p.N = append(p.N, table(rng.Float64))
+	}
+	p.Beta = table(func() float64 { return 0.5 + rng.Float64() })
+	p.Volume = table(rng.Float64)
+	return p
+}
+
+func TestBuild(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	rows, cols := 10, 12
+	p := scene(rng, rows, cols)
+	p.N[0].SetMissing(2, 3)
+
+	rules := DefaultRules()
+	rules.MinFraction = map[string]float64{"d": 0.2}
+	rules.MaxFraction = map[string]float64{"s": 0.9}
+	rules.MinBeta = 0.7
+	rules.MinAltitude, rules.MaxAltitude = 1015, 1075
+	mask, err := Build(p, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := p.Classes.ByName("d")
+	s, _ := p.Classes.ByName("s")
+	for i := range rows {
+		for j := range cols {
+			k := i*cols + j
+			want := p.N[d.Column].Valid(i, j) && p.N[d.Column].Data[k] >= 0.2 && p.N[s.Column].Data[k] <= 0.9 &&
+				p.Beta.Data[k] >= 0.7 && i >= 2 && i <= 7
+			if mask[k] != want {
+				t.Errorf("точка (%d, %d): %v, ожидалось %v", i, j, mask[k], want)
+			}
+		}
+	}
+
+	// Декорреляция: d и u совпадают в левой половине сцены
+	for i := range rows {
+		for j := range cols / 2 {
+			p.N[1].Set(i, j, p.N[0].Get(i, j))
+		}
+	}
+	rules = DefaultRules()
+	rules.MinDecorrelation, rules.Radius = 0.5, 1
+	mask, err = Build(p, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rows {
+		if mask[i*cols] || mask[i*cols+1] {
+			t.Errorf("строка %d: коррелированные точки не исключены", i)
+		}
+	}
+	if Count(mask) == 0 {
+		t.Error("исключены все точки")
+	}
+
+	rules = DefaultRules()
+	rules.MinFraction = map[string]float64{"x": 0.1}
+	if _, err := Build(p, rules); err == nil {
+		t.Error("ожидалась ошибка для неизвестного класса")
+	}
+	p.N[0].RowLabels[0] = "верх"
+	rules = DefaultRules()
+	rules.MaxAltitude = 1050
+	if _, err := Build(p, rules); err == nil {
+		t.Error("ожидалась ошибка для нечисловой метки высоты")
+	}
+}
+
+func TestLargestComponents(t *testing.T) {
+	// Диагональный слой связен, одиночная точка и пара отбрасываются
+	pattern := []string{
+		"x.....#",
+		".x....#",
+		"..x..#.",
+		"...x...",
+		"xx..x..",
+	}
+	rows, cols := len(pattern), len(pattern[0])
+	mask := make([]bool, rows*cols)
+	for i, line := range pattern {
+		for j, c := range line {
+			mask[i*cols+j] = c != '.'
+		}
+	}
+	labels, sizes := Components(mask, rows, cols)
+	if !reflect.DeepEqual(sizes, []int{5, 3, 2}) {
+		t.Fatalf("размеры компонент %v", sizes)
+	}
+	if labels[0] != 0 || labels[4*cols+4] != 0 || labels[6] != 1 || labels[1] != -1 {
+		t.Errorf("метки %v", labels)
+	}
+
+	kept, got := LargestComponents(mask, rows, cols, 0, 3)
+	if !reflect.DeepEqual(got, []int{5, 3}) || Count(kept) != 8 || kept[4*cols] {
+		t.Errorf("компоненты %v, точек %d", got, Count(kept))
+	}
+	kept, got = LargestComponents(mask, rows, cols, 1, 1)
+	if !reflect.DeepEqual(got, []int{5}) || kept[6] || !kept[3*cols+3] {
+		t.Errorf("наибольшая компонента %v", got)
+	}
+}
+
+func TestRectangles(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	rows, cols := 9, 11
+	tables := make([]*mat.Dense, 3)
+	for a := range tables {
+		tables[a] = mat.NewDense(rows, cols, nil)
+		for i := range rows {
+			for j := range cols {
+				tables[a].Set(i, j, rng.Float64())
+			}
+		}
+	}
+	tables[1].Set(4, 4, math.NaN())
+	mask := make([]bool, rows*cols)
+	for k := range mask {
+		mask[k] = k%cols != 5
+	}
+
+	opts := statistics.RegionOptions{MinSize: 3}
+	regions, union, err := Rectangles(tables, mask, 3, statistics.ObjectiveMax, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 3 {
+		t.Fatalf("найдено %d областей", len(regions))
+	}
+	// Первая область - лучшая область среди точек маски
+	masked := make([]*mat.Dense, len(tables))
+	for a, tb := range tables {
+		masked[a] = mat.DenseCopyOf(tb)
+		for k, ok := range mask {
+			if !ok {
+				masked[a].Set(k/cols, k%cols, math.NaN())
+			}
+		}
+	}
+	best, err := statistics.FindRegions(masked, statistics.ObjectiveMax, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, r := best[0], regions[0]; b.R1 != r.R1 || b.C1 != r.C1 || b.R2 != r.R2 || b.C2 != r.C2 {
+		t.Errorf("первая область %+v, ожидалась %+v", r, b)
+	}
+
+	// Сумма площадей равна числу точек объединения, только если области
+	// не имеют общих точек
+
+	count := make([]int, rows*cols)
+	area := 0
+	for _, r := range regions {
+		area += r.Area
+		for i := r.R1; i <= r.R2; i++ {
+			for j := r.C1; j <= r.C2; j++ {
+				k := i*cols + j
+				if mask[k] && !math.IsNaN(tables[1].At(i, j)) {
+					count[k]++
+				}
+			}
+		}
+	}
+	for k, c := range count {
+		if union[k] && (!mask[k] || c == 0) {
+			t.Errorf("точка %d в объединении вне маски или областей", k)
+		}
+	}
+	if Count(union) != area {
+		t.Errorf("точек в объединении %d, сумма площадей %d", Count(union), area)
+	}
+
+	if _, _, err := Rectangles(tables, mask[1:], 1, statistics.ObjectiveMax, opts); err == nil {
+		t.Error("ожидалась ошибка для маски неверного размера")
+	}
+}
@@ -0,0 +1,68 @@
+package solver
+
+import (
+	"classification-project/internal/models"
+	"classification-project/pkg/math/statistics"
+	"runtime"
+)
+
+// Bootstrap оценивает неопределенность коэффициентов бутстрепом по точкам области:
+// в каждой выборке точки берутся с возвращением, и по ним решается объединенная
+// система выбранным методом. Параметр регуляризации - p.Lambda или, если задан
+// автоматический способ выбора, λ, выбранный один раз по всем точкам области
+func (s *Solver) Bootstrap(p models.InputParameters) (statistics.BootstrapResult, error) {
+	if p.Classes == nil {
+		p.Classes = models.DefaultClasses
+	}
+	if p.Metric == "" {
+		p.Metric = MetricRelL2
+	}
+	if err := validateSigma(p); err != nil {
+		return statistics.BootstrapResult{}, err
+	}
+	if err := validateSelection(p); err != nil {
+		return statistics.BootstrapResult{}, err
+	}
+	if p.LambdaMethod != "" && p.LambdaMethod != LambdaFixed {
+		if err := ValidateLambdaMethod(p.LambdaMethod); err != nil {
+			return statistics.BootstrapResult{}, err
+		}
+		curve, err := s.pooledLambda(p)
+		if err != nil {
+			return statistics.BootstrapResult{}, err
+		}
+		p.Lambda = curve.Lambda()
+		s.logger.Info("λ для бутстрепа выбран по всем точкам области", "method", p.LambdaMethod, "lambda", p.Lambda)
+	}
+	ls, err := NewLinearSolver(p.Method, p.Lambda)
+	if err != nil {
+		return statistics.BootstrapResult{}, err
+	}
+	nWorkers := p.NWorkers
+	if nWorkers <= 0 {
+		nWorkers = runtime.NumCPU()
+	}
+
+	pixels := ValidIndices(p)
+	estimator := func(sample []int) ([]float64, error) {
+		indices := make([]models.Index, len(sample))
+		for k, i := range sample {
+			indices[k] = pixels[i]
+		}
+		lp, err := s.linearProblem(p, indices, ls)
+		if err != nil {
+			return nil, err
+		}
+		sol, err := lp.Solve()
+		if err != nil {
+			return nil, err
+		}
+		cv := make([]float64, len(sol.Cv))
+		for i := range cv {
+			cv[i] = sol.Cv[i] / scaleFactor
+		}
+		return cv, nil
+	}
+
+	return statistics.Bootstrap(len(pixels), p.NBootstrap, p.BootstrapMethod, s.rng, nWorkers, estimator)
+}
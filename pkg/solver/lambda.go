@@ -0,0 +1,177 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Способы выбора параметра регуляризации λ
+const (
+	LambdaFixed       = "fixed"       // значение из -lambda
+	LambdaLCurve      = "lcurve"      // угол L-кривой (максимум кривизны)
+	LambdaGCV         = "gcv"         // обобщенная перекрестная проверка
+	LambdaDiscrepancy = "discrepancy" // принцип невязки Морозова
+)
+
+// LambdaMethods перечисляет все способы выбора λ
+var LambdaMethods = []string{LambdaFixed, LambdaLCurve, LambdaGCV, LambdaDiscrepancy}
+
+// ValidateLambdaMethod проверяет имя способа выбора λ
+func ValidateLambdaMethod(method string) error {
+	for _, m := range LambdaMethods {
+		if m == method {
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестный способ выбора λ %q, допустимые: %s", method, strings.Join(LambdaMethods, ", "))
+}
+
+// Область, по которой выбирается λ
+const (
+	LambdaScopeDraw   = "draw"   // отдельно для каждой выборки Монте-Карло
+	LambdaScopePooled = "pooled" // один раз по всем точкам области
+)
+
+// lambdaGridSize - число точек логарифмической сетки λ
+const lambdaGridSize = 60
+
+// LambdaCurve содержит значения критерия выбора λ на сетке
+type LambdaCurve struct {
+	Method        string
+	Lambdas       []float64
+	ResidualNorms []float64 // ||A x_λ - b||₂
+	SolutionNorms []float64 // ||x_λ||₂
+	Criterion     []float64 // GCV: G(λ), L-кривая: кривизна, Морозов: ||r|| - δ
+	Best          int       // индекс выбранного λ
+}
+
+// Lambda возвращает выбранное значение параметра регуляризации
+func (c LambdaCurve) Lambda() float64 {
+	return c.Lambdas[c.Best]
+}
+
+// SelectLambda выбирает параметр регуляризации для системы A x = b.
+// noise - относительный уровень шума правой части, используется только
+// принципом невязки: ищется λ, при котором ||A x_λ - b|| = noise·||b||
+func SelectLambda(A *mat.Dense, b *mat.VecDense, method string, noise float64) (LambdaCurve, error) {
+	m, _ := A.Dims()
+
+	var svd mat.SVD
+	if !svd.Factorize(A, mat.SVDThin) {
+		return LambdaCurve{}, fmt.Errorf("SVD-разложение не сошлось")
+	}
+	var U mat.Dense
+	svd.UTo(&U)
+	sigma := svd.Values(nil)
+	if sigma[0] == 0 {
+		return LambdaCurve{}, fmt.Errorf("нулевая матрица системы")
+	}
+
+	// Коэффициенты разложения правой части по левым сингулярным векторам
+	beta := make([]float64, len(sigma))
+	bNorm2 := mat.Dot(b, b)
+	outside := bNorm2 // часть ||b||², лежащая вне образа A
+	for i := range sigma {
+		beta[i] = mat.Dot(U.ColView(i), b)
+		outside -= beta[i] * beta[i]
+	}
+	outside = math.Max(outside, 0)
+
+	// Логарифмическая сетка от σ_max²·1e-12 до σ_max²
+	lo, hi := math.Log(sigma[0]*sigma[0]*1e-12), math.Log(sigma[0]*sigma[0])
+	curve := LambdaCurve{
+		Method:        method,
+		Lambdas:       make([]float64, lambdaGridSize),
+		ResidualNorms: make([]float64, lambdaGridSize),
+		SolutionNorms: make([]float64, lambdaGridSize),
+		Criterion:     make([]float64, lambdaGridSize),
+	}
+	dof := make([]float64, lambdaGridSize)
+	for k := range lambdaGridSize {
+		lambda := math.Exp(lo + (hi-lo)*float64(k)/float64(lambdaGridSize-1))
+		var r2, x2, trace float64
+		for i, s := range sigma {
+			if s == 0 {
+				r2 += beta[i] * beta[i]
+				continue
+			}
+			f := s * s / (s*s + lambda)
+			r2 += (1 - f) * (1 - f) * beta[i] * beta[i]
+			x2 += f * f * beta[i] * beta[i] / (s * s)
+			trace += f
+		}
+		r2 += outside
+		curve.Lambdas[k] = lambda
+		curve.ResidualNorms[k] = math.Sqrt(r2)
+		curve.SolutionNorms[k] = math.Sqrt(x2)
+		dof[k] = float64(m) - trace
+	}
+
+	switch method {
+	case LambdaGCV:
+		curve.Best = 0
+		for k := range curve.Lambdas {
+			if dof[k] <= 0 {
+				curve.Criterion[k] = math.Inf(1)
+			} else {
+				curve.Criterion[k] = curve.ResidualNorms[k] * curve.ResidualNorms[k] / (dof[k] * dof[k])
+			}
+			if curve.Criterion[k] < curve.Criterion[curve.Best] {
+				curve.Best = k
+			}
+		}
+	case LambdaLCurve:
+		lCurveCurvature(&curve)
+	case LambdaDiscrepancy:
+		if noise <= 0 {
+			return LambdaCurve{}, fmt.Errorf("для принципа невязки нужен положительный уровень шума")
+		}
+		delta := noise * math.Sqrt(bNorm2)
+		curve.Best = 0
+		for k := range curve.Lambdas {
+			curve.Criterion[k] = curve.ResidualNorms[k] - delta
+			if math.Abs(curve.Criterion[k]) < math.Abs(curve.Criterion[curve.Best]) {
+				curve.Best = k
+			}
+		}
+	default:
+		return LambdaCurve{}, fmt.Errorf("неизвестный способ выбора λ %q, допустимые: %s",
+			method, strings.Join(LambdaMethods[1:], ", "))
+	}
+
+	return curve, nil
+}
+
+// lCurveCurvature вычисляет кривизну L-кривой (log||r||, log||x||) по параметру
+// log λ конечными разностями и выбирает точку максимальной кривизны
+func lCurveCurvature(curve *LambdaCurve) {
+	n := len(curve.Lambdas)
+	rho := make([]float64, n)
+	eta := make([]float64, n)
+	for k := range n {
+		rho[k] = math.Log(math.Max(curve.ResidualNorms[k], math.SmallestNonzeroFloat64))
+		eta[k] = math.Log(math.Max(curve.SolutionNorms[k], math.SmallestNonzeroFloat64))
+	}
+
+	curve.Best = n / 2
+	best := math.Inf(-1)
+	for k := 1; k < n-1; k++ {
+		dr := (rho[k+1] - rho[k-1]) / 2
+		de := (eta[k+1] - eta[k-1]) / 2
+		ddr := rho[k+1] - 2*rho[k] + rho[k-1]
+		dde := eta[k+1] - 2*eta[k] + eta[k-1]
+		den := math.Pow(dr*dr+de*de, 1.5)
+		if den == 0 {
+			continue
+		}
+		// Знак выбран так, чтобы угол L-кривой давал максимум
+		curve.Criterion[k] = (dr*dde - ddr*de) / den
+		if curve.Criterion[k] > best {
+			best = curve.Criterion[k]
+			curve.Best = k
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package solver
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"testing"
+
+	"classification-project/internal/models"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// hilbertProblem строит плохо обусловленную систему с матрицей Гильберта
+// и зашумленной правой частью
+func hilbertProblem(noise float64) (*mat.Dense, *mat.VecDense) {
+	rng := rand.New(rand.NewSource(3))
+	m, n := 40, 8
+	A := mat.NewDense(m, n, nil)
+	for i := range m {
+		for j := range n {
+			A.Set(i, j, 1/float64(i+j+1))
+		}
+	}
+	b := mat.NewVecDense(m, nil)
+	for i := range m {
+		sum := 0.0
+		for j := range n {
+			sum += A.At(i, j)
+		}
+		b.SetVec(i, sum*(1+noise*rng.NormFloat64()))
+	}
+	return A, b
+}
+
+func TestSelectLambda(t *testing.T) {
+	noise := 0.01
+	A, b := hilbertProblem(noise)
+
+	for _, method := range []string{LambdaGCV, LambdaLCurve, LambdaDiscrepancy} {
+		t.Run(method, func(t *testing.T) {
+			curve, err := SelectLambda(A, b, method, noise)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			if curve.Best <= 0 || curve.Best >= len(curve.Lambdas)-1 {
+				t.Errorf("выбран граничный λ: индекс %d", curve.Best)
+			}
+
+			// Норма невязки, посчитанная через SVD, должна совпадать с прямым расчетом
+			x, err := solveRegularizedQR(A, b, curve.Lambda())
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			r := mat.NewVecDense(b.Len(), nil)
+			r.MulVec(A, x)
+			r.SubVec(r, b)
+			if got, want := curve.ResidualNorms[curve.Best], mat.Norm(r, 2); math.Abs(got-want) > 1e-8*want {
+				t.Errorf("норма невязки: получено %g, ожидалось %g", got, want)
+			}
+		})
+	}
+}
+
+func TestSelectLambdaDiscrepancy(t *testing.T) {
+	noise := 0.01
+	A, b := hilbertProblem(noise)
+
+	curve, err := SelectLambda(A, b, LambdaDiscrepancy, noise)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	delta := noise * mat.Norm(b, 2)
+	// Невязка монотонна по λ, поэтому выбранная точка лежит рядом с корнем
+	if k := curve.Best; curve.ResidualNorms[k-1] > delta || curve.ResidualNorms[k+1] < delta {
+		t.Errorf("||r|| = %g не окружает δ = %g", curve.ResidualNorms[k], delta)
+	}
+
+	if _, err := SelectLambda(A, b, LambdaDiscrepancy, 0); err == nil {
+		t.Error("ожидалась ошибка при нулевом уровне шума")
+	}
+}
+
+// TestSolveLambdaErrors проверяет, что Solve сообщает об ошибке вместо
+// нулевого Cv, если способ выбора λ неизвестен или ни одна выборка не решена
+func TestSolveLambdaErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeScene(t, dir, map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}, 6, 5)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	p := readScene(dir, models.DefaultClasses)
+	p.LambdaMethod = "foo"
+	if _, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p); err == nil {
+		t.Error("ожидалась ошибка для неизвестного способа выбора λ")
+	}
+
+	// Принцип невязки без уровня шума отбрасывает каждую выборку
+	p.LambdaMethod, p.LambdaScope, p.NoiseLevel = LambdaDiscrepancy, LambdaScopeDraw, 0
+	if res, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p); err == nil {
+		t.Errorf("ожидалась ошибка без успешных выборок, получено Cv = %v", res.Cv)
+	}
+}
+
+// TestBootstrapSelectedLambda проверяет, что при автоматическом выборе λ
+// бутстреп использует выбранный параметр, а не значение -lambda
+func TestBootstrapSelectedLambda(t *testing.T) {
+	dir := t.TempDir()
+	writeScene(t, dir, map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}, 6, 5)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	p := readScene(dir, models.DefaultClasses)
+	noise := rand.New(rand.NewSource(5))
+	for k := range p.Volume.Data {
+		p.Volume.Data[k] *= 1 + 0.05*noise.NormFloat64()
+	}
+	p.NBootstrap, p.BootstrapMethod, p.NWorkers = 20, "percentile", 1
+	p.Lambda, p.LambdaMethod = 10, LambdaGCV
+
+	auto, err := NewSolver(logger, rand.New(rand.NewSource(1))).Bootstrap(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	curve, err := NewSolver(logger, rand.New(rand.NewSource(1))).pooledLambda(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curve.Lambda() == p.Lambda {
+		t.Fatalf("выбранный λ совпал с фиксированным %g", p.Lambda)
+	}
+
+	fixed := p
+	fixed.LambdaMethod, fixed.Lambda = LambdaFixed, curve.Lambda()
+	want, err := NewSolver(logger, rand.New(rand.NewSource(1))).Bootstrap(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want.Estimate {
+		if auto.Estimate[i] != want.Estimate[i] {
+			t.Errorf("Estimate[%d] = %g, с выбранным λ %g", i, auto.Estimate[i], want.Estimate[i])
+		}
+	}
+
+	fixed.Lambda = p.Lambda
+	stale, err := NewSolver(logger, rand.New(rand.NewSource(1))).Bootstrap(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(stale.Estimate[0]-auto.Estimate[0]) < 1e-9*math.Abs(auto.Estimate[0]) {
+		t.Errorf("оценка с λ = %g совпадает с оценкой при выбранном λ", p.Lambda)
+	}
+}
+
+// TestSolveLambdaScopeDefault проверяет, что пустая область выбора λ
+// означает выбор для каждой выборки, как в командной строке
+func TestSolveLambdaScopeDefault(t *testing.T) {
+	dir := t.TempDir()
+	writeScene(t, dir, map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}, 6, 5)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	p := readScene(dir, models.DefaultClasses)
+	noise := rand.New(rand.NewSource(5))
+	for k := range p.Volume.Data {
+		p.Volume.Data[k] *= 1 + 0.05*noise.NormFloat64()
+	}
+	p.NIters, p.NumPointsToAvg, p.LambdaMethod = 20, 5, LambdaGCV
+
+	got, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p)
+	if err != nil {
+		t.Fatalf("пустая область выбора λ: %v", err)
+	}
+	p.LambdaScope = LambdaScopeDraw
+	want, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Lambda != want.Lambda || got.Cv[0] != want.Cv[0] {
+		t.Errorf("λ %g, Cv[0] %g; для draw: λ %g, Cv[0] %g", got.Lambda, got.Cv[0], want.Lambda, want.Cv[0])
+	}
+
+	p.LambdaScope = "region"
+	if _, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p); err == nil {
+		t.Error("ожидалась ошибка для неизвестной области выбора λ")
+	}
+}
@@ -0,0 +1,194 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Методы решения линейной системы, выбираемые флагом -method
+const (
+	MethodCholesky   = "chol" // нормальные уравнения, разложение Холецкого
+	MethodQR         = "qr"   // QR-разложение расширенной системы
+	MethodSVD        = "svd"  // усеченное сингулярное разложение
+	MethodLU         = "lu"   // нормальные уравнения, LU-разложение
+	MethodNelderMead = "nm"   // минимизация относительной невязки Нелдером-Мидом
+	MethodNNLS       = "nnls" // МНК с ограничениями x >= 0 (Лоусон-Хансон)
+)
+
+// Methods перечисляет все поддерживаемые методы
+var Methods = []string{MethodCholesky, MethodQR, MethodSVD, MethodLU, MethodNelderMead, MethodNNLS}
+
+// svdTolerance - относительный порог отсечения сингулярных чисел
+const svdTolerance = 1e-10
+
+// LinearSolution - общий результат для всех линейных решателей
+type LinearSolution struct {
+	X            *mat.VecDense
+	ResidualNorm float64 // ||A x - b||₂
+	Cond         float64 // число обусловленности A в норме 2
+	Rank         int     // численный ранг A
+	Active       []bool  // активные ограничения x_i >= 0 (только для NNLS)
+}
+
+// LinearSolver решает переопределенную систему A x = b
+type LinearSolver interface {
+	Name() string
+	SolveLS(A *mat.Dense, b *mat.VecDense) (LinearSolution, error)
+}
+
+// NewLinearSolver создает решатель по имени метода
+func NewLinearSolver(method string, lambda float64) (LinearSolver, error) {
+	switch method {
+	case MethodCholesky:
+		return &CholeskySolver{Lambda: lambda}, nil
+	case MethodQR:
+		return &QRSolver{Lambda: lambda}, nil
+	case MethodSVD:
+		return &SVDSolver{Lambda: lambda, Tol: svdTolerance}, nil
+	case MethodLU:
+		return &LUSolver{Lambda: lambda}, nil
+	case MethodNelderMead:
+		return &NelderMeadSolver{Lambda: lambda}, nil
+	case MethodNNLS:
+		return &NNLSSolver{Lambda: lambda}, nil
+	}
+	return nil, fmt.Errorf("неизвестный метод %q, допустимые: %s", method, strings.Join(Methods, ", "))
+}
+
+// CholeskySolver решает нормальные уравнения (AᵀA + λI) x = Aᵀb разложением Холецкого
+type CholeskySolver struct {
+	Lambda float64
+}
+
+func (s *CholeskySolver) Name() string { return MethodCholesky }
+
+func (s *CholeskySolver) SolveLS(A *mat.Dense, b *mat.VecDense) (LinearSolution, error) {
+	x, err := solveRegularizedLS(A, b, s.Lambda)
+	if err != nil {
+		return LinearSolution{}, err
+	}
+	return newLinearSolution(A, b, x), nil
+}
+
+// LUSolver решает нормальные уравнения (AᵀA + λI) x = Aᵀb LU-разложением
+type LUSolver struct {
+	Lambda float64
+}
+
+func (s *LUSolver) Name() string { return MethodLU }
+
+func (s *LUSolver) SolveLS(A *mat.Dense, b *mat.VecDense) (LinearSolution, error) {
+	x, err := solveRegularizedLU(A, b, s.Lambda)
+	if err != nil {
+		return LinearSolution{}, err
+	}
+	return newLinearSolution(A, b, x), nil
+}
+
+// QRSolver решает расширенную систему [A; sqrt(λ)I] x = [b; 0] QR-разложением,
+// не возводя матрицу в квадрат
+type QRSolver struct {
+	Lambda float64
+}
+
+func (s *QRSolver) Name() string { return MethodQR }
+
+func (s *QRSolver) SolveLS(A *mat.Dense, b *mat.VecDense) (LinearSolution, error) {
+	x, err := solveRegularizedQR(A, b, s.Lambda)
+	if err != nil {
+		return LinearSolution{}, err
+	}
+	return newLinearSolution(A, b, x), nil
+}
+
+// SVDSolver решает систему через сингулярное разложение, отбрасывая
+// сингулярные числа меньше Tol·σ_max и применяя фильтр Тихонова σ²/(σ²+λ)
+type SVDSolver struct {
+	Lambda float64
+	Tol    float64
+}
+
+func (s *SVDSolver) Name() string { return MethodSVD }
+
+func (s *SVDSolver) SolveLS(A *mat.Dense, b *mat.VecDense) (LinearSolution, error) {
+	x, rank, err := solveTruncatedSVD(A, b, s.Lambda, s.Tol)
+	if err != nil {
+		return LinearSolution{}, err
+	}
+	sol := newLinearSolution(A, b, x)
+	sol.Rank = rank
+	return sol, nil
+}
+
+// NelderMeadSolver минимизирует сумму квадратов относительных невязок
+// со штрафом λ||x||² методом Нелдера-Мида, начиная с решения регуляризованного МНК
+type NelderMeadSolver struct {
+	Lambda float64
+}
+
+func (s *NelderMeadSolver) Name() string { return MethodNelderMead }
+
+func (s *NelderMeadSolver) SolveLS(A *mat.Dense, b *mat.VecDense) (LinearSolution, error) {
+	x0, err := solveRegularizedQR(A, b, s.Lambda)
+	if err != nil {
+		return LinearSolution{}, err
+	}
+	p := &Problem{A: A, b: b, lambda: s.Lambda}
+	result, err := p.optimize(x0.RawVector().Data)
+	if err != nil {
+		return LinearSolution{}, err
+	}
+	return newLinearSolution(A, b, mat.NewVecDense(len(result.X), result.X)), nil
+}
+
+// NNLSSolver решает регуляризованную задачу МНК с ограничениями x >= 0
+type NNLSSolver struct {
+	Lambda float64
+}
+
+func (s *NNLSSolver) Name() string { return MethodNNLS }
+
+func (s *NNLSSolver) SolveLS(A *mat.Dense, b *mat.VecDense) (LinearSolution, error) {
+	x, active, err := solveNNLS(A, b, s.Lambda)
+	if err != nil {
+		return LinearSolution{}, err
+	}
+	sol := newLinearSolution(A, b, x)
+	sol.Active = active
+	return sol, nil
+}
+
+// newLinearSolution заполняет общие поля результата: норму невязки,
+// число обусловленности и численный ранг матрицы A
+func newLinearSolution(A *mat.Dense, b, x *mat.VecDense) LinearSolution {
+	r := mat.NewVecDense(b.Len(), nil)
+	r.MulVec(A, x)
+	r.SubVec(r, b)
+
+	cond, rank := conditioning(A, svdTolerance)
+	return LinearSolution{
+		X:            x,
+		ResidualNorm: mat.Norm(r, 2),
+		Cond:         cond,
+		Rank:         rank,
+	}
+}
+
+// conditioning возвращает число обусловленности и численный ранг матрицы
+func conditioning(A *mat.Dense, tol float64) (float64, int) {
+	var svd mat.SVD
+	if !svd.Factorize(A, mat.SVDNone) {
+		return math.Inf(1), 0
+	}
+	values := svd.Values(nil)
+	rank := 0
+	for _, v := range values {
+		if v > tol*values[0] {
+			rank++
+		}
+	}
+	return svd.Cond(), rank
+}
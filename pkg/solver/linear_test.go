@@ -0,0 +1,130 @@
+package solver
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TestLinearSolversAgree проверяет, что все методы на хорошо обусловленной
+// совместной системе дают одно решение и заполняют диагностику
+func TestLinearSolversAgree(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	m, n := 12, 3
+	A := mat.NewDense(m, n, nil)
+	for i := range m {
+		for j := range n {
+			A.Set(i, j, rng.Float64())
+		}
+		A.Set(i, i%n, A.At(i, i%n)+2)
+	}
+	truth := mat.NewVecDense(n, []float64{1.5, 0.7, 2.3})
+	b := mat.NewVecDense(m, nil)
+	b.MulVec(A, truth)
+
+	var svd mat.SVD
+	if !svd.Factorize(A, mat.SVDNone) {
+		t.Fatal("SVD-разложение не сошлось")
+	}
+	wantCond := svd.Cond()
+
+	for _, tt := range []struct {
+		method string
+		tol    float64
+	}{
+		{MethodCholesky, 1e-9},
+		{MethodQR, 1e-9},
+		{MethodSVD, 1e-9},
+		{MethodLU, 1e-9},
+		{MethodNNLS, 1e-9},
+		{MethodNelderMead, 1e-4},
+	} {
+		t.Run(tt.method, func(t *testing.T) {
+			ls, err := NewLinearSolver(tt.method, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sol, err := ls.SolveLS(A, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j := range n {
+				if d := math.Abs(sol.X.AtVec(j) - truth.AtVec(j)); d > tt.tol*truth.AtVec(j) {
+					t.Errorf("x[%d] = %.10g, ожидалось %.10g", j, sol.X.AtVec(j), truth.AtVec(j))
+				}
+			}
+			if sol.Rank != n {
+				t.Errorf("ранг %d, ожидался %d", sol.Rank, n)
+			}
+			if math.Abs(sol.Cond-wantCond) > 1e-9*wantCond {
+				t.Errorf("число обусловленности %g, ожидалось %g", sol.Cond, wantCond)
+			}
+			r := mat.NewVecDense(m, nil)
+			r.MulVec(A, sol.X)
+			r.SubVec(r, b)
+			if want := mat.Norm(r, 2); math.Abs(sol.ResidualNorm-want) > 1e-12 || sol.ResidualNorm > tt.tol*mat.Norm(b, 2) {
+				t.Errorf("норма невязки %g, по решению %g", sol.ResidualNorm, want)
+			}
+		})
+	}
+}
+
+// TestNelderMeadLambda проверяет, что Нелдер-Мид учитывает штраф λ||x||²
+func TestNelderMeadLambda(t *testing.T) {
+	A := mat.NewDense(4, 2, []float64{
+		1, 0.9,
+		0.9, 1,
+		1, 1,
+		0.5, 0.6,
+	})
+	b := mat.NewVecDense(4, []float64{2, 2.1, 2.2, 1.1})
+
+	ls, err := NewLinearSolver(MethodNelderMead, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := solverLambda(ls); got != 0.5 {
+		t.Fatalf("solverLambda = %g, ожидалось 0.5", got)
+	}
+	plain, err := (&NelderMeadSolver{}).SolveLS(A, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	penalized, err := ls.SolveLS(A, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mat.Norm(penalized.X, 2) >= mat.Norm(plain.X, 2) {
+		t.Errorf("||x|| со штрафом %g не меньше, чем без штрафа %g",
+			mat.Norm(penalized.X, 2), mat.Norm(plain.X, 2))
+	}
+
+	p := &Problem{A: A, b: b, lambda: 0.5}
+	x := penalized.X.RawVector().Data
+	for j := range x {
+		for _, h := range []float64{-1e-3, 1e-3} {
+			y := append([]float64(nil), x...)
+			y[j] += h
+			if p.Func(y) < p.Func(x)-1e-12 {
+				t.Errorf("сдвиг x[%d] на %g уменьшает целевую функцию", j, h)
+			}
+		}
+	}
+}
+
+func TestMedian(t *testing.T) {
+	for _, tt := range []struct {
+		values []float64
+		want   float64
+	}{
+		{[]float64{3}, 3},
+		{[]float64{5, 1, math.Inf(1)}, 5},
+		{[]float64{4, 1, 3, 2}, 2.5},
+	} {
+		if got := median(tt.values); got != tt.want {
+			t.Errorf("median(%v) = %g, ожидалось %g", tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package solver
+
+import (
+	"classification-project/internal/models"
+	"classification-project/pkg/math/statistics"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Типы априорных распределений коэффициентов S_i
+const (
+	PriorFlat      = "flat"        // равномерное на S > 0
+	PriorLogNormal = "lognormal"   // log S ~ N(Mu, Sigma²)
+	PriorTruncNorm = "truncnormal" // S ~ N(Mean, SD²), усеченное на [Lower, Upper]
+)
+
+// Модели шума правой части V/β
+const (
+	NoiseGaussian = "gaussian" // σ_i = Sigma (в единицах V/β)
+	NoiseRelative = "relative" // σ_i = Sigma·|V/β|
+	NoiseStudent  = "student"  // t-распределение Стьюдента с Nu степенями свободы и σ_i = Sigma·|V/β|
+)
+
+// Алгоритмы выборки
+const (
+	SamplerMetropolis = "metropolis" // Метрополис-Гастингс с фиксированным гауссовым предложением
+	SamplerAdaptive   = "adaptive"   // адаптивный Метрополис (Haario et al.), адаптация на прогреве
+)
+
+// PriorConfig - априорное распределение одного коэффициента (в физических единицах)
+type PriorConfig struct {
+	Type  string  `json:"type"`
+	Mu    float64 `json:"mu"`    // lognormal: среднее log S
+	Sigma float64 `json:"sigma"` // lognormal: стандартное отклонение log S
+	Mean  float64 `json:"mean"`  // truncnormal: среднее
+	SD    float64 `json:"sd"`    // truncnormal: стандартное отклонение
+	Lower float64 `json:"lower"` // truncnormal: нижняя граница (по умолчанию 0)
+	Upper float64 `json:"upper"` // truncnormal: верхняя граница (0 - без ограничения)
+}
+
+// NoiseConfig - модель шума правой части
+type NoiseConfig struct {
+	Model string  `json:"model"`
+	Sigma float64 `json:"sigma"`
+	Nu    float64 `json:"nu"` // только для student
+}
+
+// MCMCConfig - параметры выборки из апостериорного распределения
+type MCMCConfig struct {
+	Sampler string                 `json:"sampler"`
+	Chains  int                    `json:"chains"`
+	Samples int                    `json:"samples"` // число сохраняемых шагов на цепочку
+	BurnIn  int                    `json:"burn_in"`
+	Thin    int                    `json:"thin"`
+	Scale   float64                `json:"proposal_scale"` // начальный шаг относительно начальной точки
+	Noise   NoiseConfig            `json:"noise"`
+	Priors  map[string]PriorConfig `json:"priors"` // по именам классов; нет записи - flat
+}
+
+// LoadMCMCConfig читает конфигурацию из JSON-файла и заполняет значения по умолчанию
+func LoadMCMCConfig(filename string) (MCMCConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return MCMCConfig{}, err
+	}
+	cfg := MCMCConfig{
+		Sampler: SamplerAdaptive,
+		Chains:  4,
+		Samples: 5000,
+		BurnIn:  2000,
+		Thin:    1,
+		Scale:   0.1,
+		Noise:   NoiseConfig{Model: NoiseRelative, Sigma: 0.05, Nu: 4},
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return MCMCConfig{}, fmt.Errorf("ошибка разбора %s: %v", filename, err)
+	}
+	return cfg, cfg.validate()
+}
+
+func (cfg MCMCConfig) validate() error {
+	if cfg.Sampler != SamplerMetropolis && cfg.Sampler != SamplerAdaptive {
+		return fmt.Errorf("неизвестный алгоритм выборки %q", cfg.Sampler)
+	}
+	if cfg.Chains < 2 {
+		return fmt.Errorf("для R-hat нужно не меньше двух цепочек, задано %d", cfg.Chains)
+	}
+	if cfg.Samples < 10 || cfg.BurnIn < 0 || cfg.Thin < 1 || cfg.Scale <= 0 {
+		return fmt.Errorf("неверные samples=%d, burn_in=%d, thin=%d, proposal_scale=%g",
+			cfg.Samples, cfg.BurnIn, cfg.Thin, cfg.Scale)
+	}
+	switch cfg.Noise.Model {
+	case NoiseGaussian, NoiseRelative:
+	case NoiseStudent:
+		if cfg.Noise.Nu <= 0 {
+			return fmt.Errorf("для модели student нужно nu > 0")
+		}
+	default:
+		return fmt.Errorf("неизвестная модель шума %q", cfg.Noise.Model)
+	}
+	if cfg.Noise.Sigma <= 0 {
+		return fmt.Errorf("уровень шума должен быть положительным")
+	}
+	for name, prior := range cfg.Priors {
+		switch prior.Type {
+		case PriorFlat:
+		case PriorLogNormal:
+			if prior.Sigma <= 0 {
+				return fmt.Errorf("априорное распределение %s: sigma должно быть положительным", name)
+			}
+		case PriorTruncNorm:
+			if prior.SD <= 0 {
+				return fmt.Errorf("априорное распределение %s: sd должно быть положительным", name)
+			}
+		default:
+			return fmt.Errorf("априорное распределение %s: неизвестный тип %q", name, prior.Type)
+		}
+	}
+	return nil
+}
+
+// logDensity возвращает логарифм априорной плотности (с точностью до константы)
+func (prior PriorConfig) logDensity(s float64) float64 {
+	switch prior.Type {
+	case PriorLogNormal:
+		if s <= 0 {
+			return math.Inf(-1)
+		}
+		z := (math.Log(s) - prior.Mu) / prior.Sigma
+		return -0.5*z*z - math.Log(s)
+	case PriorTruncNorm:
+		if s < prior.Lower || (prior.Upper > prior.Lower && s > prior.Upper) {
+			return math.Inf(-1)
+		}
+		z := (s - prior.Mean) / prior.SD
+		return -0.5 * z * z
+	}
+	if s <= 0 {
+		return math.Inf(-1)
+	}
+	return 0
+}
+
+// typicalScale возвращает характерный масштаб коэффициента по априорному
+// распределению (0 для flat)
+func (prior PriorConfig) typicalScale() float64 {
+	switch prior.Type {
+	case PriorLogNormal:
+		return math.Exp(prior.Mu)
+	case PriorTruncNorm:
+		return math.Max(math.Abs(prior.Mean), prior.SD)
+	}
+	return 0
+}
+
+// ChainDiagnostics - диагностика сходимости для одного коэффициента
+type ChainDiagnostics struct {
+	RHat float64 // потенциальный коэффициент сокращения масштаба Гельмана-Рубина
+	ESS  float64 // эффективный размер выборки по всем цепочкам
+}
+
+// MCMCResult содержит апостериорную выборку и диагностику цепочек
+type MCMCResult struct {
+	Samples     []models.OutputSolution // все сохраненные шаги всех цепочек (Cv в единицах решателя)
+	Mean        []float64               // апостериорное среднее (физические единицы)
+	SD          []float64
+	CI68        [][2]float64
+	CI95        [][2]float64
+	Diagnostics []ChainDiagnostics
+	Acceptance  []float64 // доля принятых предложений для каждой цепочки
+}
+
+// posterior - логарифм апостериорной плотности для системы Σ n_i S_i = V/β
+type posterior struct {
+	A      *mat.Dense
+	b      *mat.VecDense
+	sigma  []float64
+	weight []float64 // 1/σ_i², веса метрики chi2
+	noise  NoiseConfig
+	priors []PriorConfig
+	metric string
+}
+
+func (post *posterior) logDensity(theta []float64) (float64, float64) {
+	lp := 0.0
+	for i, prior := range post.priors {
+		lp += prior.logDensity(theta[i] / scaleFactor)
+		if math.IsInf(lp, -1) {
+			return lp, math.Inf(1)
+		}
+	}
+
+	r := mat.NewVecDense(post.b.Len(), nil)
+	r.MulVec(post.A, mat.NewVecDense(len(theta), theta))
+	r.SubVec(r, post.b)
+	for i := range r.Len() {
+		z := r.AtVec(i) / post.sigma[i]
+		if post.noise.Model == NoiseStudent {
+			lp -= 0.5 * (post.noise.Nu + 1) * math.Log1p(z*z/post.noise.Nu)
+		} else {
+			lp -= 0.5 * z * z
+		}
+	}
+	// Метрика проверена в SamplePosterior, а веса chi2 заданы
+	metric, _ := residualMetric(post.metric, r.RawVector().Data, post.b.RawVector().Data, post.weight)
+	return lp, metric
+}
+
+// SamplePosterior строит цепочки Маркова для апостериорного распределения
+// коэффициентов по всем уравнениям области
+func (s *Solver) SamplePosterior(p models.InputParameters, cfg MCMCConfig) (MCMCResult, error) {
+	if err := cfg.validate(); err != nil {
+		return MCMCResult{}, err
+	}
+	if p.Classes == nil {
+		p.Classes = models.DefaultClasses
+	}
+	if p.Metric == "" {
+		p.Metric = MetricRelL2
+	}
+	if err := ValidateMetric(p.Metric); err != nil {
+		return MCMCResult{}, err
+	}
+	if err := validateSelection(p); err != nil {
+		return MCMCResult{}, err
+	}
+	// Опечатка в имени класса иначе молча заменила бы априорное распределение на flat
+	for name := range cfg.Priors {
+		if _, ok := p.Classes.ByName(name); !ok {
+			return MCMCResult{}, fmt.Errorf("априорное распределение для неизвестного класса %q, допустимые: %s",
+				name, strings.Join(p.Classes.Names(), ", "))
+		}
+	}
+
+	A, b := s.buildSystem(p, ValidIndices(p))
+	post := &posterior{A: A, b: b, noise: cfg.Noise, metric: p.Metric,
+		sigma: make([]float64, b.Len()), weight: make([]float64, b.Len()), priors: make([]PriorConfig, p.Classes.Len())}
+	for i := range post.sigma {
+		post.sigma[i] = cfg.Noise.Sigma * scaleFactor
+		if cfg.Noise.Model != NoiseGaussian {
+			post.sigma[i] = cfg.Noise.Sigma * math.Abs(b.AtVec(i))
+		}
+		if post.sigma[i] == 0 {
+			return MCMCResult{}, fmt.Errorf("нулевая погрешность в уравнении %d", i)
+		}
+		post.weight[i] = 1 / (post.sigma[i] * post.sigma[i])
+	}
+	for _, c := range p.Classes.Classes() {
+		post.priors[c.Column] = PriorConfig{Type: PriorFlat}
+		if prior, ok := cfg.Priors[c.Name]; ok {
+			post.priors[c.Column] = prior
+		}
+	}
+
+	// Начальная точка - решение МНК, приведенное в допустимую область
+	x0, err := solveRegularizedQR(A, b, p.Lambda)
+	if err != nil {
+		return MCMCResult{}, err
+	}
+	dim := p.Classes.Len()
+	start := make([]float64, dim)
+	for i := range start {
+		start[i] = math.Max(x0.AtVec(i), 1e-3*mat.Norm(x0, math.Inf(1)))
+	}
+	steps, err := proposalSteps(post, start, cfg.Scale)
+	if err != nil {
+		return MCMCResult{}, err
+	}
+
+	seeds := make([]int64, cfg.Chains)
+	for i := range seeds {
+		seeds[i] = s.rng.Int63()
+	}
+	chains := make([][][]float64, cfg.Chains)
+	discr := make([][]float64, cfg.Chains)
+	acceptance := make([]float64, cfg.Chains)
+	nWorkers := p.NWorkers
+	if nWorkers <= 0 {
+		nWorkers = runtime.NumCPU()
+	}
+	nWorkers = min(nWorkers, cfg.Chains)
+
+	// Каждая цепочка использует свое зерно, поэтому результат не зависит от числа потоков
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range nWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				chains[c], discr[c], acceptance[c] = runChain(post, cfg, start, steps, rand.New(rand.NewSource(seeds[c])))
+			}
+		}()
+	}
+	for c := range cfg.Chains {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+
+	res := MCMCResult{
+		Mean:        make([]float64, dim),
+		SD:          make([]float64, dim),
+		CI68:        make([][2]float64, dim),
+		CI95:        make([][2]float64, dim),
+		Diagnostics: make([]ChainDiagnostics, dim),
+		Acceptance:  acceptance,
+	}
+	for c := range chains {
+		for k, theta := range chains[c] {
+			res.Samples = append(res.Samples, models.OutputSolution{
+				Cv:          theta,
+				Discrepancy: discr[c][k],
+				Metric:      p.Metric,
+				Method:      "mcmc-" + cfg.Sampler,
+			})
+		}
+	}
+
+	values := make([]float64, len(res.Samples))
+	for i := range dim {
+		for k, sol := range res.Samples {
+			values[k] = sol.Cv[i] / scaleFactor
+		}
+		mean, sd := meanStd(values)
+		res.Mean[i], res.SD[i] = mean, sd
+		sort.Float64s(values)
+		res.CI68[i] = [2]float64{statistics.Quantile(values, 0.16), statistics.Quantile(values, 0.84)}
+		res.CI95[i] = [2]float64{statistics.Quantile(values, 0.025), statistics.Quantile(values, 0.975)}
+		res.Diagnostics[i] = chainDiagnostics(chains, i)
+	}
+	return res, nil
+}
+
+// proposalSteps возвращает начальные стандартные отклонения предложения: долю
+// scale от модуля начальной точки, а для нулевой компоненты - от характерного
+// масштаба априорного распределения или, для flat, от ‖b‖/‖A_i‖ по столбцу системы.
+// Нулевой шаг не дал бы цепочке сдвинуться, поэтому он считается ошибкой
+func proposalSteps(post *posterior, start []float64, scale float64) ([]float64, error) {
+	bNorm := mat.Norm(post.b, 2)
+	steps := make([]float64, len(start))
+	for i := range steps {
+		base := math.Abs(start[i])
+		if base == 0 {
+			base = post.priors[i].typicalScale() * scaleFactor
+		}
+		if base == 0 {
+			if colNorm := mat.Norm(post.A.ColView(i), 2); colNorm > 0 {
+				base = bNorm / colNorm
+			}
+		}
+		steps[i] = scale * base
+		if steps[i] <= 0 || math.IsInf(steps[i], 0) || math.IsNaN(steps[i]) {
+			return nil, fmt.Errorf("вырожденная ковариация предложения для коэффициента %d: "+
+				"начальная точка и правая часть системы нулевые, задайте априорное распределение", i)
+		}
+	}
+	return steps, nil
+}
+
+// runChain выполняет одну цепочку и возвращает сохраненные шаги,
+// значения невязки и долю принятых предложений
+func runChain(post *posterior, cfg MCMCConfig, start, steps []float64, rng *rand.Rand) ([][]float64, []float64, float64) {
+	dim := len(start)
+	theta := append([]float64(nil), start...)
+	lp, d := post.logDensity(theta)
+
+	// Начальное предложение диагональное, шаги положительны (см. proposalSteps)
+	propCov := mat.NewSymDense(dim, nil)
+	for i := range dim {
+		propCov.SetSym(i, i, steps[i]*steps[i])
+	}
+	var chol mat.Cholesky
+	chol.Factorize(propCov)
+
+	// Накопители для адаптивной ковариации
+	sd := 2.38 * 2.38 / float64(dim)
+	sum := make([]float64, dim)
+	sumOuter := mat.NewSymDense(dim, nil)
+	adaptStart := max(cfg.BurnIn/10, 2*dim)
+
+	total := cfg.BurnIn + cfg.Samples*cfg.Thin
+	samples := make([][]float64, 0, cfg.Samples)
+	discr := make([]float64, 0, cfg.Samples)
+	accepted := 0
+	z := make([]float64, dim)
+	step := mat.NewVecDense(dim, nil)
+	var L mat.TriDense
+	chol.LTo(&L)
+	for it := range total {
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		step.MulVec(&L, mat.NewVecDense(dim, z))
+		proposal := make([]float64, dim)
+		for i := range proposal {
+			proposal[i] = theta[i] + step.AtVec(i)
+		}
+
+		lpNew, dNew := post.logDensity(proposal)
+		if !math.IsInf(lpNew, -1) && math.Log(rng.Float64()) < lpNew-lp {
+			theta, lp, d = proposal, lpNew, dNew
+			if it >= cfg.BurnIn {
+				accepted++
+			}
+		}
+
+		if it < cfg.BurnIn && cfg.Sampler == SamplerAdaptive {
+			for i := range dim {
+				sum[i] += theta[i]
+				for j := i; j < dim; j++ {
+					sumOuter.SetSym(i, j, sumOuter.At(i, j)+theta[i]*theta[j])
+				}
+			}
+			// Ковариация истории шагов, масштабированная по Haario, плюс ε·I
+			if n := float64(it + 1); it+1 >= adaptStart {
+				cov := mat.NewSymDense(dim, nil)
+				for i := range dim {
+					for j := i; j < dim; j++ {
+						c := (sumOuter.At(i, j) - sum[i]*sum[j]/n) / (n - 1)
+						if i == j {
+							c += 1e-10 * (theta[i]*theta[i] + 1e-30)
+						}
+						cov.SetSym(i, j, sd*c)
+					}
+				}
+				if chol.Factorize(cov) {
+					chol.LTo(&L)
+				}
+			}
+		}
+
+		if it >= cfg.BurnIn && (it-cfg.BurnIn)%cfg.Thin == 0 {
+			samples = append(samples, append([]float64(nil), theta...))
+			discr = append(discr, d)
+		}
+	}
+	return samples, discr, float64(accepted) / float64(total-cfg.BurnIn)
+}
+
+// chainDiagnostics вычисляет R-hat Гельмана-Рубина и эффективный размер выборки
+// для компоненты i по набору цепочек одинаковой длины
+func chainDiagnostics(chains [][][]float64, i int) ChainDiagnostics {
+	m := float64(len(chains))
+	n := len(chains[0])
+	means := make([]float64, len(chains))
+	vars := make([]float64, len(chains))
+	series := make([][]float64, len(chains))
+	for c, chain := range chains {
+		series[c] = make([]float64, n)
+		for k, theta := range chain {
+			series[c][k] = theta[i]
+		}
+		means[c], vars[c] = meanStd(series[c])
+		vars[c] *= vars[c]
+	}
+
+	grand, _ := meanStd(means)
+	B, W := 0.0, 0.0
+	for c := range chains {
+		B += (means[c] - grand) * (means[c] - grand)
+		W += vars[c]
+	}
+	B *= float64(n) / (m - 1)
+	W /= m
+	varPlus := float64(n-1)/float64(n)*W + B/float64(n)
+	diag := ChainDiagnostics{RHat: math.Sqrt(varPlus / W)}
+	if W == 0 {
+		diag.RHat = math.NaN()
+	}
+
+	// ESS по суммам автокорреляций с начальной положительной последовательностью Гейера
+	rho := func(lag int) float64 {
+		v := 0.0
+		for c := range chains {
+			for k := 0; k+lag < n; k++ {
+				v += (series[c][k] - means[c]) * (series[c][k+lag] - means[c])
+			}
+		}
+		v /= m * float64(n)
+		return 1 - (W-v)/varPlus
+	}
+	tau := -1.0
+	for lag := 0; lag+1 < n; lag += 2 {
+		pair := rho(lag) + rho(lag+1)
+		if pair <= 0 {
+			break
+		}
+		tau += 2 * pair
+	}
+	diag.ESS = m * float64(n) / math.Max(tau, 1e-12)
+	return diag
+}
+
+// meanStd возвращает среднее и выборочное стандартное отклонение
+func meanStd(x []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range x {
+		mean += v
+	}
+	mean /= float64(len(x))
+	ss := 0.0
+	for _, v := range x {
+		ss += (v - mean) * (v - mean)
+	}
+	if len(x) < 2 {
+		return mean, 0
+	}
+	return mean, math.Sqrt(ss / float64(len(x)-1))
+}
+
+// PrintMCMC выводит апостериорные оценки коэффициентов и диагностику цепочек
+func PrintMCMC(res MCMCResult, names []string) {
+	fmt.Println("=== Апостериорное распределение (MCMC) ===")
+	fmt.Printf("%-8s %11s %11s %25s %25s %8s %10s\n", "", "mean", "sd", "68% CrI", "95% CrI", "R-hat", "ESS")
+	for i, name := range names {
+		fmt.Printf("%-8s %11.3e %11.3e [%11.3e, %11.3e] [%11.3e, %11.3e] %8.4f %10.1f\n",
+			"Cv["+name+"]", res.Mean[i], res.SD[i],
+			res.CI68[i][0], res.CI68[i][1], res.CI95[i][0], res.CI95[i][1],
+			res.Diagnostics[i].RHat, res.Diagnostics[i].ESS)
+	}
+	fmt.Print("Acceptance rate:")
+	for _, a := range res.Acceptance {
+		fmt.Printf(" %.3f", a)
+	}
+	fmt.Println()
+}
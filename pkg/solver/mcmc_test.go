@@ -0,0 +1,226 @@
+package solver
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"classification-project/internal/interface/reader"
+	"classification-project/internal/models"
+)
+
+func TestSamplePosterior(t *testing.T) {
+	truth := map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}
+	dir := t.TempDir()
+	writeScene(t, dir, truth, 8, 7)
+
+	p := models.InputParameters{
+		Classes: models.DefaultClasses,
+		N:       make([]*models.Table, 3),
+		Beta:    reader.ReadTableOrPanic(filepath.Join(dir, "beta.txt")),
+		Volume:  reader.ReadTableOrPanic(filepath.Join(dir, "Vol.txt")),
+	}
+	for _, c := range p.Classes.Classes() {
+		p.N[c.Column] = reader.ReadTableOrPanic(filepath.Join(dir, c.File))
+	}
+
+	cfg := MCMCConfig{
+		Sampler: SamplerAdaptive,
+		Chains:  4,
+		Samples: 2000,
+		BurnIn:  2000,
+		Thin:    1,
+		Scale:   0.1,
+		Noise:   NoiseConfig{Model: NoiseRelative, Sigma: 0.01},
+		Priors: map[string]PriorConfig{
+			"d": {Type: PriorLogNormal, Mu: math.Log(3e6), Sigma: 1},
+			"s": {Type: PriorTruncNorm, Mean: 1e6, SD: 1e6},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	res, err := NewSolver(logger, rand.New(rand.NewSource(1))).SamplePosterior(p, cfg)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	if len(res.Samples) != cfg.Chains*cfg.Samples {
+		t.Errorf("число шагов: получено %d, ожидалось %d", len(res.Samples), cfg.Chains*cfg.Samples)
+	}
+	for _, c := range p.Classes.Classes() {
+		i := c.Column
+		if lo, hi := res.CI95[i][0], res.CI95[i][1]; truth[c.Name] < lo || truth[c.Name] > hi {
+			t.Errorf("Cv[%s] = %.4e вне 95%% интервала [%.4e, %.4e]", c.Name, truth[c.Name], lo, hi)
+		}
+		if d := res.Diagnostics[i]; d.RHat > 1.1 || d.ESS < 100 {
+			t.Errorf("Cv[%s]: плохая сходимость, R-hat %.3f, ESS %.1f", c.Name, d.RHat, d.ESS)
+		}
+	}
+	for c, a := range res.Acceptance {
+		if a < 0.05 || a > 0.9 {
+			t.Errorf("цепочка %d: доля принятых предложений %.3f", c, a)
+		}
+	}
+}
+
+// TestSamplePosteriorMetropolis проверяет алгоритм с фиксированным предложением
+// и независимость результата от числа потоков
+func TestSamplePosteriorMetropolis(t *testing.T) {
+	truth := map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}
+	dir := t.TempDir()
+	writeScene(t, dir, truth, 8, 7)
+	p := readScene(dir, models.DefaultClasses)
+
+	cfg := MCMCConfig{
+		Sampler: SamplerMetropolis,
+		Chains:  3,
+		Samples: 3000,
+		BurnIn:  1000,
+		Thin:    2,
+		Scale:   0.005,
+		Noise:   NoiseConfig{Model: NoiseRelative, Sigma: 0.01},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	run := func(nWorkers int) MCMCResult {
+		p.NWorkers = nWorkers
+		res, err := NewSolver(logger, rand.New(rand.NewSource(2))).SamplePosterior(p, cfg)
+		if err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+		return res
+	}
+	res := run(1)
+
+	if len(res.Samples) != cfg.Chains*cfg.Samples {
+		t.Errorf("число шагов: получено %d, ожидалось %d", len(res.Samples), cfg.Chains*cfg.Samples)
+	}
+	for _, sol := range res.Samples {
+		if sol.Method != "mcmc-"+SamplerMetropolis {
+			t.Fatalf("метод %q", sol.Method)
+		}
+	}
+	for _, c := range p.Classes.Classes() {
+		if lo, hi := res.CI95[c.Column][0], res.CI95[c.Column][1]; truth[c.Name] < lo || truth[c.Name] > hi {
+			t.Errorf("Cv[%s] = %.4e вне 95%% интервала [%.4e, %.4e]", c.Name, truth[c.Name], lo, hi)
+		}
+	}
+	for c, a := range res.Acceptance {
+		if a <= 0 || a >= 1 {
+			t.Errorf("цепочка %d: доля принятых предложений %.3f", c, a)
+		}
+	}
+
+	parallel := run(3)
+	for i := range res.Mean {
+		if res.Mean[i] != parallel.Mean[i] {
+			t.Errorf("Mean[%d]: %g при одном потоке, %g при трех", i, res.Mean[i], parallel.Mean[i])
+		}
+	}
+}
+
+func TestLoadMCMCConfig(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "mcmc.json")
+	data := `{
+		"sampler": "metropolis",
+		"chains": 3,
+		"noise": {"sigma": 0.02},
+		"priors": {
+			"d": {"type": "lognormal", "mu": 15, "sigma": 0.5},
+			"s": {"type": "truncnormal", "mean": 1e6, "sd": 2e5, "upper": 5e6}
+		}
+	}`
+	if err := os.WriteFile(filename, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadMCMCConfig(filename)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if cfg.Sampler != SamplerMetropolis || cfg.Chains != 3 {
+		t.Errorf("sampler %q, chains %d", cfg.Sampler, cfg.Chains)
+	}
+	// Незаданные поля сохраняют значения по умолчанию
+	if cfg.Samples != 5000 || cfg.BurnIn != 2000 || cfg.Thin != 1 || cfg.Scale != 0.1 {
+		t.Errorf("значения по умолчанию не сохранены: %+v", cfg)
+	}
+	if cfg.Noise.Model != NoiseRelative || cfg.Noise.Sigma != 0.02 {
+		t.Errorf("модель шума %+v", cfg.Noise)
+	}
+	want := map[string]PriorConfig{
+		"d": {Type: PriorLogNormal, Mu: 15, Sigma: 0.5},
+		"s": {Type: PriorTruncNorm, Mean: 1e6, SD: 2e5, Upper: 5e6},
+	}
+	if len(cfg.Priors) != len(want) {
+		t.Fatalf("априорные распределения %+v", cfg.Priors)
+	}
+	for name, prior := range want {
+		if cfg.Priors[name] != prior {
+			t.Errorf("%s: %+v, ожидалось %+v", name, cfg.Priors[name], prior)
+		}
+	}
+
+	bad := `{"priors": {"d": {"type": "lognormal", "sigma": 0}}}`
+	if err := os.WriteFile(filename, []byte(bad), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMCMCConfig(filename); err == nil {
+		t.Error("ожидалась ошибка для lognormal с sigma = 0")
+	}
+}
+
+func TestSamplePosteriorUnknownPrior(t *testing.T) {
+	dir := t.TempDir()
+	writeScene(t, dir, map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}, 6, 5)
+	p := readScene(dir, models.DefaultClasses)
+	cfg := MCMCConfig{
+		Sampler: SamplerAdaptive, Chains: 2, Samples: 10, Thin: 1, Scale: 0.1,
+		Noise:  NoiseConfig{Model: NoiseRelative, Sigma: 0.05},
+		Priors: map[string]PriorConfig{"dust": {Type: PriorFlat}},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := NewSolver(logger, rand.New(rand.NewSource(1))).SamplePosterior(p, cfg); err == nil {
+		t.Error("ожидалась ошибка для априорного распределения неизвестного класса")
+	}
+}
+
+// TestSamplePosteriorZeroStart проверяет цепочку из нулевой начальной точки:
+// шаг предложения берется из априорного распределения, а без него - ошибка
+func TestSamplePosteriorZeroStart(t *testing.T) {
+	dir := t.TempDir()
+	writeScene(t, dir, map[string]float64{"d": 0, "u": 0, "s": 0}, 6, 5)
+	p := readScene(dir, models.DefaultClasses)
+	cfg := MCMCConfig{
+		Sampler: SamplerAdaptive, Chains: 2, Samples: 500, BurnIn: 500, Thin: 1, Scale: 0.1,
+		Noise: NoiseConfig{Model: NoiseGaussian, Sigma: 1e6},
+		Priors: map[string]PriorConfig{
+			"d": {Type: PriorLogNormal, Mu: math.Log(3e6), Sigma: 1},
+			"u": {Type: PriorLogNormal, Mu: math.Log(1e6), Sigma: 1},
+			"s": {Type: PriorTruncNorm, Mean: 0, SD: 1e6},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	res, err := NewSolver(logger, rand.New(rand.NewSource(1))).SamplePosterior(p, cfg)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	for i, sd := range res.SD {
+		if sd <= 0 {
+			t.Errorf("коэффициент %d: цепочка не сдвинулась с нулевой начальной точки", i)
+		}
+	}
+	for c, a := range res.Acceptance {
+		if a == 0 {
+			t.Errorf("цепочка %d: ни одно предложение не принято", c)
+		}
+	}
+
+	cfg.Priors = nil
+	if _, err := NewSolver(logger, rand.New(rand.NewSource(1))).SamplePosterior(p, cfg); err == nil {
+		t.Error("ожидалась ошибка для вырожденной ковариации предложения")
+	}
+}
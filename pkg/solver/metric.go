@@ -0,0 +1,70 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Метрики невязки, по которым сортируются решения Монте-Карло. Невязка
+// r = A x - b считается по исходной системе Σ n_i S_i = V/β, до балансировки строк
+const (
+	MetricRelL2  = "rel-l2"  // sqrt(Σ (r_i/b_i)²)
+	MetricAbsL2  = "abs-l2"  // sqrt(Σ r_i²)
+	MetricL1     = "l1"      // Σ |r_i|
+	MetricMaxAbs = "max-abs" // max |r_i|
+	MetricChi2   = "chi2"    // Σ w_i r_i², w_i = 1/σ_i² (требует погрешностей уравнений)
+)
+
+// Metrics перечисляет все поддерживаемые метрики
+var Metrics = []string{MetricRelL2, MetricAbsL2, MetricL1, MetricMaxAbs, MetricChi2}
+
+// ValidateMetric проверяет имя метрики
+func ValidateMetric(metric string) error {
+	for _, m := range Metrics {
+		if m == metric {
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестная метрика невязки %q, допустимые: %s", metric, strings.Join(Metrics, ", "))
+}
+
+// residualMetric вычисляет невязку r = A x - b по всем уравнениям системы.
+// weights (веса 1/σ_i²) обязательны для chi2 и не используются остальными метриками
+func residualMetric(metric string, r, b, weights []float64) (float64, error) {
+	sum := 0.0
+	switch metric {
+	case MetricRelL2:
+		for i := range r {
+			sum += math.Pow(r[i]/b[i], 2)
+		}
+		return math.Sqrt(sum), nil
+	case MetricAbsL2:
+		for i := range r {
+			sum += r[i] * r[i]
+		}
+		return math.Sqrt(sum), nil
+	case MetricL1:
+		for i := range r {
+			sum += math.Abs(r[i])
+		}
+		return sum, nil
+	case MetricMaxAbs:
+		for i := range r {
+			sum = math.Max(sum, math.Abs(r[i]))
+		}
+		return sum, nil
+	case MetricChi2:
+		if weights == nil {
+			return 0, errChi2Sigma
+		}
+		for i := range r {
+			sum += weights[i] * r[i] * r[i]
+		}
+		return sum, nil
+	}
+	return 0, ValidateMetric(metric)
+}
+
+// errChi2Sigma - ошибка метрики chi2 без погрешностей уравнений
+var errChi2Sigma = fmt.Errorf("метрика %s требует погрешностей уравнений (таблиц погрешностей)", MetricChi2)
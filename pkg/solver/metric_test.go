@@ -0,0 +1,146 @@
+package solver
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"testing"
+
+	"classification-project/internal/models"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestResidualMetric(t *testing.T) {
+	r := []float64{1, -2, 2}
+	b := []float64{2, 4, -1}
+	weights := []float64{1, 0.25, 4}
+	for _, tt := range []struct {
+		metric string
+		want   float64
+	}{
+		{MetricRelL2, math.Sqrt(0.25 + 0.25 + 4)},
+		{MetricAbsL2, 3},
+		{MetricL1, 5},
+		{MetricMaxAbs, 2},
+		{MetricChi2, 1 + 1 + 16},
+	} {
+		got, err := residualMetric(tt.metric, r, b, weights)
+		if err != nil || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s = %v, %v, ожидалось %v", tt.metric, got, err, tt.want)
+		}
+	}
+	if _, err := residualMetric("l3", r, b, weights); err == nil {
+		t.Error("ожидалась ошибка для неизвестной метрики")
+	}
+	if _, err := residualMetric(MetricChi2, r, b, nil); err == nil {
+		t.Error("ожидалась ошибка для chi2 без весов")
+	}
+}
+
+// TestLinearProblemMetrics проверяет, что метрики считаются по исходной
+// системе, а не по системе с отнормированными строками
+func TestLinearProblemMetrics(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	m, n := 7, 3
+	A := mat.NewDense(m, n, nil)
+	b := mat.NewVecDense(m, nil)
+	sigma := make([]float64, m)
+	for i := range m {
+		for j := range n {
+			A.Set(i, j, 0.1+rng.Float64()*float64(i+1))
+		}
+		b.SetVec(i, 1+5*rng.Float64())
+		sigma[i] = 0.1 + rng.Float64()
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	residual := func(cv []float64) ([]float64, []float64) {
+		r := mat.NewVecDense(m, nil)
+		r.MulVec(A, mat.NewVecDense(n, cv))
+		r.SubVec(r, b)
+		return r.RawVector().Data, b.RawVector().Data
+	}
+	for _, metric := range []string{"", MetricRelL2, MetricAbsL2, MetricL1, MetricMaxAbs, MetricChi2} {
+		var lp *LinearProblem
+		var err error
+		if metric == MetricChi2 {
+			lp, err = NewWeightedLinearProblem(A, b, sigma, WeightUnit, &QRSolver{}, metric, logger)
+		} else {
+			lp, err = NewLinearProblem(A, b, &QRSolver{}, metric, logger)
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", metric, err)
+		}
+		sol, err := lp.Solve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, bb := residual(sol.Cv)
+		weights := make([]float64, m)
+		for i, s := range sigma {
+			weights[i] = 1 / (s * s)
+		}
+		want, _ := residualMetric(sol.Metric, r, bb, weights)
+		if math.Abs(sol.Discrepancy-want) > 1e-9*want {
+			t.Errorf("%q: невязка %.12g, по исходной системе %.12g", metric, sol.Discrepancy, want)
+		}
+	}
+
+	if _, err := NewLinearProblem(A, b, &QRSolver{}, "l3", logger); err == nil {
+		t.Error("ожидалась ошибка для неизвестной метрики")
+	}
+	if _, err := NewLinearProblem(A, b, &QRSolver{}, MetricChi2, logger); err == nil {
+		t.Error("ожидалась ошибка для chi2 без погрешностей")
+	}
+}
+
+// TestSolveDiscrepancyAllEquations повторяет единственную выборку Solve с
+// NPoints = 8 и проверяет, что невязка учитывает все уравнения, а не три первых
+func TestSolveDiscrepancyAllEquations(t *testing.T) {
+	dir := t.TempDir()
+	writeScene(t, dir, map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}, 6, 5)
+	p := readScene(dir, models.DefaultClasses)
+	noise := rand.New(rand.NewSource(2))
+	for k := range p.Volume.Data {
+		p.Volume.Data[k] *= 1 + 0.05*noise.NormFloat64()
+	}
+	p.NPoints, p.NIters, p.NumPointsToAvg = 8, 1, 1
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	res, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Та же выборка: зерно итерации - первое число генератора решателя
+	s := NewSolver(logger, rand.New(rand.NewSource(1)))
+	draw := rand.New(rand.NewSource(s.rng.Int63()))
+	indices := s.generateIndices(draw, ValidMask(p), p.N[0].Rows, p.N[0].Columns, p.NPoints)
+	ls, err := NewLinearSolver(p.Method, p.Lambda)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lp, err := s.linearProblem(p, indices, ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sol, err := lp.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	A, b := s.buildSystem(p, indices)
+	r := mat.NewVecDense(b.Len(), nil)
+	r.MulVec(A, mat.NewVecDense(len(sol.Cv), sol.Cv))
+	r.SubVec(r, b)
+	all, _ := residualMetric(MetricRelL2, r.RawVector().Data, b.RawVector().Data, nil)
+	first, _ := residualMetric(MetricRelL2, r.RawVector().Data[:3], b.RawVector().Data[:3], nil)
+
+	if math.Abs(res.Discrepancy-all) > 1e-12*all {
+		t.Errorf("невязка Solve %.12g, по всем %d уравнениям %.12g", res.Discrepancy, p.NPoints, all)
+	}
+	if math.Abs(all-first) < 1e-6*all {
+		t.Errorf("невязка по всем уравнениям %.12g совпадает с невязкой по трем первым", all)
+	}
+}
@@ -10,8 +10,9 @@ import (
 )
 
 type Problem struct {
-	A *mat.Dense
-	b *mat.VecDense
+	A      *mat.Dense
+	b      *mat.VecDense
+	lambda float64 // вес штрафа Тихонова λ||x||²
 }
 
 type Problemer interface {
@@ -27,6 +28,7 @@ func NewProblem(matrix *mat.Dense, vector *mat.VecDense) *Problem {
 }
 
 // Для вектора решений вычисляем невязку по решаемой задаче
+// и добавляем штраф Тихонова λ||x||²
 func (p *Problem) Func(x []float64) float64 {
 	rows, cols := p.A.Dims()
 	tmpb := mat.NewVecDense(rows, nil)
@@ -35,6 +37,9 @@ func (p *Problem) Func(x []float64) float64 {
 	for i := range tmpb.Len() {
 		norm += math.Pow(math.Abs(tmpb.AtVec(i)-p.b.AtVec(i))/p.b.AtVec(i), 2)
 	}
+	for _, v := range x {
+		norm += p.lambda * v * v
+	}
 	penalty := 0.0
 	for i := range len(x) {
 		if x[i] < 0 {
@@ -44,7 +49,8 @@ func (p *Problem) Func(x []float64) float64 {
 	return norm
 }
 
-func (p *Problem) Solve(xinit []float64) (models.OutputSolution, error) {
+// optimize находит минимум Func методом Нелдера-Мида
+func (p *Problem) optimize(xinit []float64) (*optimize.Result, error) {
 	pp := optimize.Problem{
 		Func: p.Func,
 	}
@@ -55,8 +61,12 @@ func (p *Problem) Solve(xinit []float64) (models.OutputSolution, error) {
 		}
 	}
 
-	result, err := optimize.Minimize(pp, xinit, &optimize.Settings{
+	return optimize.Minimize(pp, xinit, &optimize.Settings{
 		MajorIterations: 1000}, &optimize.NelderMead{})
+}
+
+func (p *Problem) Solve(xinit []float64) (models.OutputSolution, error) {
+	result, err := p.optimize(xinit)
 	if err != nil {
 		return models.OutputSolution{}, err
 	}
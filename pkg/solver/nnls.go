@@ -0,0 +1,138 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// solveNNLS решает задачу min ||A x - b||² + λ||x||² при x >= 0
+// методом активного набора Лоусона-Хансона.
+// Регуляризация Тихонова учитывается расширением системы строками sqrt(λ)·I.
+// Возвращает решение и признаки активных ограничений (x_i = 0)
+func solveNNLS(A *mat.Dense, b *mat.VecDense, lambda float64) (*mat.VecDense, []bool, error) {
+	m, n := A.Dims()
+	if m <= n {
+		return nil, nil, fmt.Errorf("ожидается переопределённая система (m > n), но m=%d, n=%d", m, n)
+	}
+
+	// Расширенная система [A; sqrt(λ)I] x = [b; 0]
+	if lambda > 0 {
+		aug := mat.NewDense(m+n, n, nil)
+		aug.Slice(0, m, 0, n).(*mat.Dense).Copy(A)
+		augb := mat.NewVecDense(m+n, nil)
+		augb.SliceVec(0, m).(*mat.VecDense).CopyVec(b)
+		sq := math.Sqrt(lambda)
+		for i := range n {
+			aug.Set(m+i, i, sq)
+		}
+		A, b = aug, augb
+		m += n
+	}
+
+	tol := 1e-10 * mat.Norm(A, 1) * float64(max(m, n))
+	passive := make([]bool, n)
+	x := mat.NewVecDense(n, nil)
+	w := mat.NewVecDense(n, nil)
+	residual := mat.NewVecDense(m, nil)
+
+	gradient := func() {
+		residual.MulVec(A, x)
+		residual.SubVec(b, residual)
+		w.MulVec(A.T(), residual)
+	}
+	gradient()
+
+	maxIter := 3 * n
+	for iter := 0; ; iter++ {
+		if iter >= maxIter {
+			return nil, nil, fmt.Errorf("NNLS не сошелся за %d итераций", maxIter)
+		}
+
+		// Выбираем переменную с максимальным градиентом среди нулевых
+		t, wmax := -1, tol
+		for j := range n {
+			if !passive[j] && w.AtVec(j) > wmax {
+				t, wmax = j, w.AtVec(j)
+			}
+		}
+		if t < 0 {
+			break
+		}
+		passive[t] = true
+
+		for {
+			z, err := solvePassive(A, b, passive)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// Если все компоненты положительны, принимаем шаг целиком
+			alpha := 1.0
+			feasible := true
+			for j := range n {
+				if passive[j] && z[j] <= tol {
+					feasible = false
+					if d := x.AtVec(j) - z[j]; d > 0 {
+						alpha = math.Min(alpha, x.AtVec(j)/d)
+					}
+				}
+			}
+			if feasible {
+				for j := range n {
+					x.SetVec(j, z[j])
+				}
+				break
+			}
+
+			// Иначе сдвигаемся до границы и исключаем обнулившиеся переменные
+			for j := range n {
+				x.SetVec(j, x.AtVec(j)+alpha*(z[j]-x.AtVec(j)))
+				if passive[j] && x.AtVec(j) <= tol {
+					passive[j] = false
+					x.SetVec(j, 0)
+				}
+			}
+		}
+		gradient()
+	}
+
+	active := make([]bool, n)
+	for j := range n {
+		active[j] = !passive[j]
+	}
+	return x, active, nil
+}
+
+// solvePassive решает задачу наименьших квадратов только по столбцам
+// из пассивного набора; остальные компоненты решения равны нулю
+func solvePassive(A *mat.Dense, b *mat.VecDense, passive []bool) ([]float64, error) {
+	m, n := A.Dims()
+	cols := make([]int, 0, n)
+	for j, p := range passive {
+		if p {
+			cols = append(cols, j)
+		}
+	}
+
+	sub := mat.NewDense(m, len(cols), nil)
+	for k, j := range cols {
+		for i := range m {
+			sub.Set(i, k, A.At(i, j))
+		}
+	}
+
+	var qr mat.QR
+	qr.Factorize(sub)
+	var y mat.VecDense
+	if err := qr.SolveVecTo(&y, false, b); err != nil {
+		return nil, fmt.Errorf("ошибка при решении подзадачи NNLS: %v", err)
+	}
+
+	z := make([]float64, n)
+	for k, j := range cols {
+		z[j] = y.AtVec(k)
+	}
+	return z, nil
+}
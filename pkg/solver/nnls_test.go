@@ -0,0 +1,85 @@
+package solver
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TestSolveNNLSKKT проверяет условия Каруша-Куна-Таккера для решения NNLS
+func TestSolveNNLSKKT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for trial := range 50 {
+		m, n := 8, 3
+		A := mat.NewDense(m, n, nil)
+		b := mat.NewVecDense(m, nil)
+		for i := range m {
+			for j := range n {
+				A.Set(i, j, rng.Float64())
+			}
+			b.SetVec(i, rng.NormFloat64())
+		}
+
+		x, active, err := solveNNLS(A, b, 0)
+		if err != nil {
+			t.Fatalf("попытка %d: неожиданная ошибка: %v", trial, err)
+		}
+
+		r := mat.NewVecDense(m, nil)
+		r.MulVec(A, x)
+		r.SubVec(b, r)
+		w := mat.NewVecDense(n, nil)
+		w.MulVec(A.T(), r)
+
+		for j := range n {
+			xj, wj := x.AtVec(j), w.AtVec(j)
+			if xj < 0 {
+				t.Errorf("попытка %d: x[%d] = %g < 0", trial, j, xj)
+			}
+			if active[j] != (xj == 0) {
+				t.Errorf("попытка %d: active[%d] = %v при x[%d] = %g", trial, j, active[j], j, xj)
+			}
+			if xj > 0 && math.Abs(wj) > 1e-8 {
+				t.Errorf("попытка %d: градиент w[%d] = %g для положительной компоненты", trial, j, wj)
+			}
+			if xj == 0 && wj > 1e-8 {
+				t.Errorf("попытка %d: градиент w[%d] = %g > 0 для активного ограничения", trial, j, wj)
+			}
+		}
+	}
+}
+
+// TestSolveNNLSMatchesUnconstrained проверяет, что при положительном решении
+// NNLS совпадает с обычным МНК с регуляризацией
+func TestSolveNNLSMatchesUnconstrained(t *testing.T) {
+	A := mat.NewDense(5, 3, []float64{
+		1, 0.2, 0.1,
+		0.3, 1, 0.2,
+		0.1, 0.4, 1,
+		0.5, 0.5, 0.2,
+		0.2, 0.3, 0.6,
+	})
+	xTrue := mat.NewVecDense(3, []float64{3, 1.5, 0.8})
+	b := mat.NewVecDense(5, nil)
+	b.MulVec(A, xTrue)
+
+	lambda := 1e-3
+	x, active, err := solveNNLS(A, b, lambda)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	xls, err := solveRegularizedLS(A, b, lambda)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	for j := range 3 {
+		if active[j] {
+			t.Errorf("ограничение %d не должно быть активным", j)
+		}
+		if math.Abs(x.AtVec(j)-xls.AtVec(j)) > 1e-8 {
+			t.Errorf("x[%d]: NNLS %.10f, МНК %.10f", j, x.AtVec(j), xls.AtVec(j))
+		}
+	}
+}
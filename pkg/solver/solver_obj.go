@@ -5,8 +5,12 @@ import (
 	"classification-project/pkg/math/statistics"
 	"fmt"
 	"log/slog"
+	"math"
 	"math/rand"
+	"runtime"
+	"slices"
 	"sort"
+	"sync"
 
 	"gonum.org/v1/gonum/mat"
 )
@@ -14,74 +18,536 @@ import (
 type Solver struct {
 	// Define fields here
 	logger *slog.Logger
+	rng    *rand.Rand // Генератор, из которого берутся зерна итераций
 }
 
-func NewSolver(logger *slog.Logger) *Solver {
+// NewSolver создает решатель. Все случайные выборки выводятся из rng,
+// поэтому при одинаковом зерне результат воспроизводится полностью
+func NewSolver(logger *slog.Logger, rng *rand.Rand) *Solver {
 	return &Solver{
 		logger: logger,
+		rng:    rng,
 	}
 }
 
+// scaleFactor переводит V/β в единицы, в которых решается система
+const scaleFactor = 1.0e-6 //mkm2cm3Tom3m3 := 1.0 //1e-12
+
+// drawResult - результат одной итерации Монте-Карло
+type drawResult struct {
+	sol   models.OutputSolution
+	iter  int // номер итерации, по нему выборку можно повторить
+	valid bool
+}
+
 func (s *Solver) Solve(p models.InputParameters) (models.OutputSolution, error) {
-	//mkm2cm3Tom3m3 := 1.0 //1e-12
-	scaleFactor := 1.0e-6
-	solutions := make([]models.OutputSolution, 0, p.NIters)
-	for _ = range p.NIters {
-		indices := s.generateIndices(p.N[0].Rows, p.N[0].Columns, p.NPoints)
-		tmpA := mat.NewDense(p.NPoints, 3, nil)
-		tmpb := mat.NewVecDense(p.NPoints, nil)
+	if p.Classes == nil {
+		p.Classes = models.DefaultClasses
+	}
+	if len(p.N) != p.Classes.Len() {
+		return models.OutputSolution{}, fmt.Errorf("число таблиц долей (%d) не совпадает с числом классов (%d)", len(p.N), p.Classes.Len())
+	}
+	if p.NPoints <= p.Classes.Len() {
+		return models.OutputSolution{}, fmt.Errorf("число точек (%d) должно быть больше числа классов (%d)", p.NPoints, p.Classes.Len())
+	}
+	if p.Metric == "" {
+		p.Metric = MetricRelL2
+	}
+	if err := ValidateMetric(p.Metric); err != nil {
+		return models.OutputSolution{}, err
+	}
+	// Ошибка в имени способа выбора λ иначе отбросила бы все выборки
+	if p.LambdaMethod != "" {
+		if err := ValidateLambdaMethod(p.LambdaMethod); err != nil {
+			return models.OutputSolution{}, err
+		}
+	}
+	if err := validateSigma(p); err != nil {
+		return models.OutputSolution{}, err
+	}
+	if err := validateSelection(p); err != nil {
+		return models.OutputSolution{}, err
+	}
 
-		for j := range p.NPoints {
-			tmpA.Set(j, 0, p.N[models.Dust].Get(indices[j].Row, indices[j].Col))
-			tmpA.Set(j, 1, p.N[models.Urban].Get(indices[j].Row, indices[j].Col))
-			tmpA.Set(j, 2, p.N[models.Smoke].Get(indices[j].Row, indices[j].Col))
-			tmpb.SetVec(j, p.N[models.Volume].Get(indices[j].Row, indices[j].Col)/p.N[models.Beta].Get(indices[j].Row, indices[j].Col)*scaleFactor)
+	valid := ValidMask(p)
+	nPixels := 0
+	for _, v := range valid {
+		if v {
+			nPixels++
 		}
+	}
+	if nPixels <= p.Classes.Len() {
+		return models.OutputSolution{}, fmt.Errorf("недостаточно точек без пропусков: %d из %d", nPixels, len(valid))
+	}
+	if nPixels < len(valid) {
+		s.logger.Info("точки с пропусками и вне выбранной маски исключены из выборки", "valid", nPixels, "total", len(valid))
+	}
 
-		//m := NewProblem(tmpA, tmpb)
-		m := NewCholProblem(tmpA, tmpb, p.Lambda, s.logger)
-		sol, err := m.Solve(nil)
-		if err == nil {
-			solutions = append(solutions, sol)
+	autoLambda := p.LambdaMethod != "" && p.LambdaMethod != LambdaFixed
+	if autoLambda && p.LambdaScope == "" {
+		p.LambdaScope = LambdaScopeDraw
+	}
+	if autoLambda && p.LambdaScope == LambdaScopePooled {
+		// Один λ для всех выборок, выбранный по всем точкам области
+		curve, err := s.pooledLambda(p)
+		if err != nil {
+			return models.OutputSolution{}, err
 		}
+		printLambdaCurve("по всей области", curve)
+		p.Lambda = curve.Lambda()
+		autoLambda = false
+	} else if autoLambda && p.LambdaScope != LambdaScopeDraw {
+		return models.OutputSolution{}, fmt.Errorf("неизвестная область выбора λ %q, допустимые: %s, %s",
+			p.LambdaScope, LambdaScopeDraw, LambdaScopePooled)
+	}
+
+	ls, err := NewLinearSolver(p.Method, p.Lambda)
+	if err != nil {
+		return models.OutputSolution{}, err
+	}
+
+	nWorkers := p.NWorkers
+	if nWorkers <= 0 {
+		nWorkers = runtime.NumCPU()
+	}
+	nWorkers = min(nWorkers, max(p.NIters, 1))
+
+	// Зерно для каждой итерации генерируется заранее, поэтому результат
+	// не зависит от числа потоков и порядка их выполнения
+	seeds := make([]int64, p.NIters)
+	for i := range seeds {
+		seeds[i] = s.rng.Int63()
+	}
+
+	draws := make([]drawResult, p.NIters)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range nWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Каждый поток использует собственный генератор
+			rng := rand.New(rand.NewSource(0))
+			for it := range jobs {
+				rng.Seed(seeds[it])
+				sol, err := s.solveDraw(p, valid, ls, autoLambda, rng)
+				// Каждая итерация пишет только в свою ячейку, блокировка не нужна
+				draws[it] = drawResult{sol: sol, iter: it, valid: err == nil}
+			}
+		}()
+	}
+	for it := range p.NIters {
+		jobs <- it
+	}
+	close(jobs)
+	wg.Wait()
 
+	solved := make([]drawResult, 0, p.NIters)
+	for _, d := range draws {
+		if d.valid {
+			solved = append(solved, d)
+		}
 	}
-	nValid := len(solutions)
+
+	nValid := len(solved)
 	fmt.Printf("Num Valid Solutions: %d\n", nValid)
-	sort.Slice(solutions, func(i, j int) bool {
-		return solutions[i].Discrepancy < solutions[j].Discrepancy
+	if nValid == 0 {
+		return models.OutputSolution{}, fmt.Errorf("ни одна из %d итераций Монте-Карло не дала решения (метод %s)", p.NIters, ls.Name())
+	}
+	sort.SliceStable(solved, func(i, j int) bool {
+		return solved[i].sol.Discrepancy < solved[j].sol.Discrepancy
 	})
+	solutions := make([]models.OutputSolution, nValid)
+	for i, d := range solved {
+		solutions[i] = d.sol
+	}
+
+	if ls.Name() == MethodNNLS {
+		printActiveConstraints(solutions)
+	}
+	if autoLambda {
+		printLambdaStatistics(p.LambdaMethod, solutions)
+		// Кривые всех выборок не хранятся: выборка с наименьшей невязкой
+		// повторяется по своему зерну, и выводится ее кривая
+		rng := rand.New(rand.NewSource(seeds[solved[0].iter]))
+		if m, err := s.drawProblem(p, valid, ls, rng); err == nil {
+			if curve, err := SelectLambda(m.A, m.b, p.LambdaMethod, p.NoiseLevel); err == nil {
+				printLambdaCurve("для выборки с наименьшей невязкой", curve)
+			}
+		}
+	}
 
 	// Простой расчет гистограмм
 	fmt.Println("=== Простой расчет гистограмм ===")
 	fmt.Printf("=== Единицы измерения для Cv  x10¹² Mm м³/м³ ===\n")
-	simpleResults := statistics.CalculateHistograms(solutions, 10)
-	statistics.PrintAllHistograms(simpleResults, 50)
+	simpleResults := statistics.CalculateHistograms(solutions, p.Classes, 10)
+	statistics.PrintHistogramsInOrder(simpleResults, statistics.HistogramNames(p.Classes), 50)
 
 	numPtsToAvg := min(nValid, p.NumPointsToAvg)
 	scale := 1.0 / float64(numPtsToAvg)
-	cfinal := make([]float64, models.TotalCv)
+	cfinal := make([]float64, p.Classes.Len())
+	var cerr []float64
+	if p.HasSigma() {
+		cerr = make([]float64, p.Classes.Len())
+	}
 	Discr := 0.0
+	resNorm, lambda := 0.0, 0.0
+	conds := make([]float64, numPtsToAvg)
+	rank := p.Classes.Len()
 	for i := range numPtsToAvg {
-		cfinal[0] += solutions[i].Cv[0] * scale / scaleFactor
-		cfinal[1] += solutions[i].Cv[1] * scale / scaleFactor
-		cfinal[2] += solutions[i].Cv[2] * scale / scaleFactor
+		for k := range cfinal {
+			cfinal[k] += solutions[i].Cv[k] * scale / scaleFactor
+		}
+		for k := range cerr {
+			cerr[k] += solutions[i].CvErr[k] * scale / scaleFactor
+		}
 		Discr += solutions[i].Discrepancy * scale
+		resNorm += solutions[i].ResidualNorm * scale
+		conds[i] = solutions[i].Cond
+		rank = min(rank, solutions[i].Rank)
+		lambda += solutions[i].Lambda * scale
 	}
 
 	return models.OutputSolution{
-		Cv:          cfinal,
-		Discrepancy: Discr,
+		Cv:           cfinal,
+		CvErr:        cerr,
+		Discrepancy:  Discr,
+		Metric:       p.Metric,
+		Method:       ls.Name(),
+		ResidualNorm: resNorm,
+		Cond:         median(conds),
+		Rank:         rank,
+		Lambda:       lambda,
 	}, nil
 }
 
-func (s *Solver) generateIndices(rows, cols, nPoints int) []models.Index {
+// median возвращает медиану значений. В отличие от среднего она не становится
+// бесконечной из-за одной вырожденной выборки с Cond = +Inf
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// pooledLambda выбирает параметр регуляризации способом p.LambdaMethod
+// по системе из всех точек области
+func (s *Solver) pooledLambda(p models.InputParameters) (LambdaCurve, error) {
+	ls, err := NewLinearSolver(p.Method, p.Lambda)
+	if err != nil {
+		return LambdaCurve{}, err
+	}
+	lp, err := s.linearProblem(p, ValidIndices(p), ls)
+	if err != nil {
+		return LambdaCurve{}, err
+	}
+	return SelectLambda(lp.A, lp.b, p.LambdaMethod, p.NoiseLevel)
+}
+
+// solveDraw выполняет одну итерацию Монте-Карло: выбирает случайные точки
+// и решает составленную по ним систему. При autoLambda параметр
+// регуляризации выбирается заново для этой выборки
+func (s *Solver) solveDraw(p models.InputParameters, valid []bool, ls LinearSolver, autoLambda bool, rng *rand.Rand) (models.OutputSolution, error) {
+	m, err := s.drawProblem(p, valid, ls, rng)
+	if err != nil {
+		return models.OutputSolution{}, err
+	}
+	lambda := p.Lambda
+	if autoLambda {
+		curve, err := SelectLambda(m.A, m.b, p.LambdaMethod, p.NoiseLevel)
+		if err != nil {
+			return models.OutputSolution{}, err
+		}
+		lambda = curve.Lambda()
+		if m.solver, err = NewLinearSolver(p.Method, lambda); err != nil {
+			return models.OutputSolution{}, err
+		}
+	}
+
+	sol, err := m.Solve()
+	sol.Lambda = lambda
+	return sol, err
+}
+
+// drawProblem выбирает случайные точки и составляет по ним систему
+func (s *Solver) drawProblem(p models.InputParameters, valid []bool, ls LinearSolver, rng *rand.Rand) (*LinearProblem, error) {
+	indices := s.generateIndices(rng, valid, p.N[0].Rows, p.N[0].Columns, p.NPoints)
+	return s.linearProblem(p, indices, ls)
+}
+
+// buildSystem составляет систему уравнений Σ n_i S_i = V/β по заданным точкам.
+// Столбцы матрицы соответствуют столбцам классов в реестре
+func (s *Solver) buildSystem(p models.InputParameters, indices []models.Index) (*mat.Dense, *mat.VecDense) {
+	tmpA := mat.NewDense(len(indices), p.Classes.Len(), nil)
+	tmpb := mat.NewVecDense(len(indices), nil)
+
+	for j := range indices {
+		for _, c := range p.Classes.Classes() {
+			tmpA.Set(j, c.Column, p.N[c.Column].Get(indices[j].Row, indices[j].Col))
+		}
+		tmpb.SetVec(j, p.Volume.Get(indices[j].Row, indices[j].Col)/p.Beta.Get(indices[j].Row, indices[j].Col)*scaleFactor)
+	}
+	return tmpA, tmpb
+}
+
+// linearProblem составляет систему по точкам indices и взвешивает ее строки
+// способом p.Weighting. Погрешность уравнения зависит от решения, если заданы
+// погрешности долей, поэтому она пересчитывается sigmaIterations раз, начиная
+// с решения системы с единичными строками
+func (s *Solver) linearProblem(p models.InputParameters, indices []models.Index, ls LinearSolver) (*LinearProblem, error) {
+	A, b := s.buildSystem(p, indices)
+	u := s.buildUncertainty(p, indices)
+	if u == nil {
+		return NewLinearProblem(A, b, ls, p.Metric, s.logger)
+	}
+
+	var x []float64
+	if u.A != nil {
+		// Метрика начального решения не используется; chi2 без погрешностей недоступна
+		lp, err := NewLinearProblem(A, b, ls, MetricRelL2, s.logger)
+		if err != nil {
+			return nil, err
+		}
+		sol, err := lp.Solve()
+		if err != nil {
+			return nil, err
+		}
+		x = sol.Cv
+	}
+	lp, err := NewWeightedLinearProblem(A, b, u.Sigma(x), p.Weighting, ls, p.Metric, s.logger)
+	for it := 1; err == nil && u.A != nil && it < sigmaIterations; it++ {
+		var sol models.OutputSolution
+		if sol, err = lp.Solve(); err != nil {
+			break
+		}
+		lp, err = NewWeightedLinearProblem(A, b, u.Sigma(sol.Cv), p.Weighting, ls, p.Metric, s.logger)
+	}
+	return lp, err
+}
+
+// buildUncertainty составляет погрешности системы buildSystem по таблицам
+// погрешностей: σ(V/β)² = (σV/β)² + (V σβ/β²)², σA_ik = σn_k. Возвращает nil,
+// если таблицы не заданы
+func (s *Solver) buildUncertainty(p models.InputParameters, indices []models.Index) *Uncertainty {
+	if !p.HasSigma() {
+		return nil
+	}
+	u := &Uncertainty{}
+	if p.SigmaBeta != nil || p.SigmaVolume != nil {
+		u.B = make([]float64, len(indices))
+		for j, idx := range indices {
+			beta, volume := p.Beta.Get(idx.Row, idx.Col), p.Volume.Get(idx.Row, idx.Col)
+			sb, sv := 0.0, 0.0
+			if p.SigmaBeta != nil {
+				sb = p.SigmaBeta.Get(idx.Row, idx.Col)
+			}
+			if p.SigmaVolume != nil {
+				sv = p.SigmaVolume.Get(idx.Row, idx.Col)
+			}
+			u.B[j] = math.Hypot(sv/beta, volume*sb/(beta*beta)) * scaleFactor
+		}
+	}
+	for k, sn := range p.SigmaN {
+		if sn == nil {
+			continue
+		}
+		if u.A == nil {
+			u.A = mat.NewDense(len(indices), p.Classes.Len(), nil)
+		}
+		for j, idx := range indices {
+			u.A.Set(j, k, sn.Get(idx.Row, idx.Col))
+		}
+	}
+	return u
+}
+
+// validateSigma проверяет согласованность таблиц погрешностей и способа взвешивания
+func validateSigma(p models.InputParameters) error {
+	if err := ValidateWeighting(p.Weighting); err != nil {
+		return err
+	}
+	if p.SigmaN != nil && len(p.SigmaN) != p.Classes.Len() {
+		return fmt.Errorf("число таблиц погрешностей долей (%d) не совпадает с числом классов (%d)", len(p.SigmaN), p.Classes.Len())
+	}
+	if p.Weighting != "" && p.Weighting != WeightUnit && !p.HasSigma() {
+		return fmt.Errorf("взвешивание %s требует таблиц погрешностей", p.Weighting)
+	}
+	if p.Metric == MetricChi2 && !p.HasSigma() {
+		return errChi2Sigma
+	}
+	return nil
+}
+
+// generateIndices выбирает nPoints случайных точек среди валидных (valid - маска
+// по строкам, см. ValidMask). Точки с пропусками и вне выбранной маски
+// отбрасываются и выбираются заново, поэтому без пропусков последовательность
+// точек не зависит от наличия маски
+func (s *Solver) generateIndices(rng *rand.Rand, valid []bool, rows, cols, nPoints int) []models.Index {
 	indices := make([]models.Index, nPoints)
 	for i := range indices {
-		indices[i] = models.Index{
-			Row: rand.Intn(rows),
-			Col: rand.Intn(cols),
+		for {
+			idx := models.Index{
+				Row: rng.Intn(rows),
+				Col: rng.Intn(cols),
+			}
+			if valid[idx.Row*cols+idx.Col] {
+				indices[i] = idx
+				break
+			}
+		}
+	}
+	return indices
+}
+
+// printActiveConstraints выводит долю решений, в которых ограничение Cv[i] >= 0
+// было активным, т.е. класс фактически отсутствует в выборке
+func printActiveConstraints(solutions []models.OutputSolution) {
+	if len(solutions) == 0 {
+		return
+	}
+	counts := make([]int, len(solutions[0].Active))
+	for _, sol := range solutions {
+		for i, a := range sol.Active {
+			if a {
+				counts[i]++
+			}
+		}
+	}
+	fmt.Println("=== Активные ограничения Cv >= 0 (доля решений) ===")
+	for i, c := range counts {
+		fmt.Printf("Cv[%d]: %d (%.1f%%)\n", i, c, 100*float64(c)/float64(len(solutions)))
+	}
+}
+
+// validateSelection проверяет размер маски выбранных точек
+func validateSelection(p models.InputParameters) error {
+	if p.Selection != nil && len(p.Selection) != p.N[0].Rows*p.N[0].Columns {
+		return fmt.Errorf("размер маски выбранных точек (%d) не совпадает с размером таблиц %dx%d",
+			len(p.Selection), p.N[0].Rows, p.N[0].Columns)
+	}
+	return nil
+}
+
+// ValidMask возвращает маску (по строкам) точек, для которых заданы доли всех
+// классов, β и V, а β != 0, т.е. V/β определено. Если заданы таблицы
+// погрешностей, погрешности в точке также должны быть заданы и неотрицательны.
+// Если задана маска p.Selection, учитываются только выбранные точки
+func ValidMask(p models.InputParameters) []bool {
+	rows, cols := p.N[0].Rows, p.N[0].Columns
+	sigmas := append([]*models.Table{p.SigmaBeta, p.SigmaVolume}, p.SigmaN...)
+	valid := make([]bool, rows*cols)
+	for i := range rows {
+		for j := range cols {
+			ok := p.Beta.Valid(i, j) && p.Beta.Get(i, j) != 0 && p.Volume.Valid(i, j)
+			ok = ok && (p.Selection == nil || p.Selection[i*cols+j])
+			for _, n := range p.N {
+				ok = ok && n.Valid(i, j)
+			}
+			for _, s := range sigmas {
+				ok = ok && (s == nil || s.Valid(i, j) && s.Get(i, j) >= 0)
+			}
+			valid[i*cols+j] = ok
+		}
+	}
+	return valid
+}
+
+// ValidIndices возвращает индексы всех точек без пропусков (см. ValidMask)
+func ValidIndices(p models.InputParameters) []models.Index {
+	cols := p.N[0].Columns
+	var indices []models.Index
+	for k, ok := range ValidMask(p) {
+		if ok {
+			indices = append(indices, models.Index{Row: k / cols, Col: k % cols})
 		}
 	}
 	return indices
 }
+
+// printLambdaCurve выводит кривую критерия выбора λ и отмечает выбранную точку;
+// where описывает систему, по которой строилась кривая
+func printLambdaCurve(where string, curve LambdaCurve) {
+	fmt.Printf("=== Выбор λ (%s) %s ===\n", curve.Method, where)
+	fmt.Printf("%10s  %10s  %10s  %10s\n", "lambda", "||r||", "||x||", "criterion")
+	for k, lambda := range curve.Lambdas {
+		mark := ""
+		if k == curve.Best {
+			mark = "  <-"
+		}
+		fmt.Printf("%10.3e  %10.3e  %10.3e  %+10.3e%s\n",
+			lambda, curve.ResidualNorms[k], curve.SolutionNorms[k], curve.Criterion[k], mark)
+	}
+	fmt.Printf("Lambda: %.3e\n", curve.Lambda())
+}
+
+// printLambdaStatistics выводит распределение λ, выбранных для отдельных выборок
+func printLambdaStatistics(method string, solutions []models.OutputSolution) {
+	if len(solutions) == 0 {
+		return
+	}
+	lambdas := make([]float64, len(solutions))
+	for i, sol := range solutions {
+		lambdas[i] = sol.Lambda
+	}
+	sort.Float64s(lambdas)
+	fmt.Printf("=== Выбор λ (%s) для каждой выборки ===\n", method)
+	fmt.Printf("min: %.3e  median: %.3e  max: %.3e\n",
+		lambdas[0], lambdas[len(lambdas)/2], lambdas[len(lambdas)-1])
+}
+
+// RelativeDiscrepancy вычисляет для каждой точки относительное отклонение
+// (Σ n_i Cv_i - V/β) / (V/β), где Cv_i берется из столбца класса в реестре
+func RelativeDiscrepancy(p models.InputParameters, cv []float64) *models.Table {
+	return pointwise(p, func(i, j int) float64 {
+		tmpR := p.Volume.Get(i, j) / p.Beta.Get(i, j)
+		return (predicted(p, cv, i, j) - tmpR) / tmpR
+	})
+}
+
+// ObservedRatio возвращает таблицу измеренного отношения V/β
+func ObservedRatio(p models.InputParameters) *models.Table {
+	return pointwise(p, func(i, j int) float64 {
+		return p.Volume.Get(i, j) / p.Beta.Get(i, j)
+	})
+}
+
+// PredictedRatio возвращает таблицу модельного отношения Σ n_i Cv_i
+func PredictedRatio(p models.InputParameters, cv []float64) *models.Table {
+	return pointwise(p, func(i, j int) float64 {
+		return predicted(p, cv, i, j)
+	})
+}
+
+// pointwise строит таблицу с метками V, вычисляя value в точках без пропусков;
+// остальные точки отмечаются пропусками
+func pointwise(p models.InputParameters, value func(i, j int) float64) *models.Table {
+	volume := p.Volume
+	r := models.NewTable(volume.Rows, volume.Columns, nil,
+		append([]string(nil), volume.ColumnLabels...),
+		append([]string(nil), volume.RowLabels...))
+
+	valid := ValidMask(p)
+	for i := range volume.Rows {
+		for j := range volume.Columns {
+			if !valid[i*volume.Columns+j] {
+				r.SetMissing(i, j)
+				continue
+			}
+			r.Set(i, j, value(i, j))
+		}
+	}
+	return r
+}
+
+// predicted вычисляет Σ n_i Cv_i в точке (i, j); p.N и cv упорядочены
+// по столбцам классов в реестре
+func predicted(p models.InputParameters, cv []float64, i, j int) float64 {
+	sum := 0.0
+	for k, n := range p.N {
+		sum += n.Get(i, j) * cv[k]
+	}
+	return sum
+}
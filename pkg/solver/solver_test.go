@@ -0,0 +1,333 @@
+package solver
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"classification-project/internal/interface/reader"
+	"classification-project/internal/models"
+)
+
+// writeTable записывает таблицу в текстовом формате с метками в кавычках
+func writeTable(t *testing.T, path string, rows, cols int, value func(i, j int) float64) {
+	t.Helper()
+	var sb strings.Builder
+	for j := range cols {
+		fmt.Fprintf(&sb, "\t\"C%d\"", j)
+	}
+	sb.WriteString("\n")
+	for i := range rows {
+		fmt.Fprintf(&sb, "\"%g\"", 1000+7.5*float64(i))
+		for j := range cols {
+			fmt.Fprintf(&sb, "\t%.17g", value(i, j))
+		}
+		sb.WriteString("\n")
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeScene записывает в dir файлы долей классов, beta.txt и Vol.txt,
+// согласованные с коэффициентами truth
+func writeScene(t *testing.T, dir string, truth map[string]float64, rows, cols int) {
+	t.Helper()
+	names := make([]string, 0, len(truth))
+	for name := range truth {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rng := rand.New(rand.NewSource(7))
+	fractions := make(map[string][]float64)
+	beta := make([]float64, rows*cols)
+	volume := make([]float64, rows*cols)
+	for k := range beta {
+		weights := make([]float64, len(names))
+		sum := 0.0
+		for i := range weights {
+			weights[i] = rng.Float64()
+			sum += weights[i]
+		}
+		beta[k] = 0.5 + rng.Float64()
+		for i, name := range names {
+			fractions[name] = append(fractions[name], weights[i]/sum)
+			volume[k] += beta[k] * truth[name] * weights[i] / sum
+		}
+	}
+
+	for name, data := range fractions {
+		writeTable(t, filepath.Join(dir, name+".txt"), rows, cols, func(i, j int) float64 { return data[i*cols+j] })
+	}
+	writeTable(t, filepath.Join(dir, "beta.txt"), rows, cols, func(i, j int) float64 { return beta[i*cols+j] })
+	writeTable(t, filepath.Join(dir, "Vol.txt"), rows, cols, func(i, j int) float64 { return volume[i*cols+j] })
+}
+
+// TestSolveClassOrdering проходит весь путь от файлов классов до Cv и матрицы
+// невязок и проверяет, что коэффициенты не перепутаны между классами
+// при любом порядке столбцов в реестре и любом числе классов
+func TestSolveClassOrdering(t *testing.T) {
+	// Различные коэффициенты, чтобы перестановка сразу была заметна
+	truth := map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}
+	rows, cols := 8, 7
+
+	dir := t.TempDir()
+	writeScene(t, dir, truth, rows, cols)
+
+	tests := []struct {
+		name    string
+		classes *models.ClassRegistry
+	}{
+		{"default", models.DefaultClasses},
+		{"permuted", models.MustClassRegistry(
+			models.Class{Name: "s", File: "s.txt", Column: 0},
+			models.Class{Name: "d", File: "d.txt", Column: 1},
+			models.Class{Name: "u", File: "u.txt", Column: 2},
+		)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkSolve(t, dir, tt.classes, truth)
+		})
+	}
+}
+
+// TestSolveArbitraryClasses проверяет систему с одним и с пятью классами
+func TestSolveArbitraryClasses(t *testing.T) {
+	for _, truth := range []map[string]float64{
+		{"d": 3.0e6},
+		{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6, "m": 2.2e6, "v": 0.4e6},
+	} {
+		t.Run(fmt.Sprintf("%d", len(truth)), func(t *testing.T) {
+			dir := t.TempDir()
+			writeScene(t, dir, truth, 8, 7)
+
+			spec := []string{}
+			for _, name := range []string{"d", "u", "s", "m", "v"} {
+				if _, ok := truth[name]; ok {
+					spec = append(spec, name)
+				}
+			}
+			classes, err := models.ParseClasses(strings.Join(spec, ","))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSolve(t, dir, classes, truth)
+		})
+	}
+}
+
+// TestSolveMissingValues проверяет, что точки с пропусками в долях и с β = 0
+// не попадают в выборку и отмечаются пропусками в матрице невязок
+func TestSolveMissingValues(t *testing.T) {
+	truth := map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}
+	rows, cols := 8, 7
+
+	dir := t.TempDir()
+	writeScene(t, dir, truth, rows, cols)
+
+	// Пропуски в d.txt и нулевая β с заведомо неверным V: если такие точки
+	// попадут в систему, решение будет далеко от truth
+	rewrite := func(name string, change func(i, j int, v float64) float64) {
+		path := filepath.Join(dir, name)
+		table := reader.ReadTableOrPanic(path)
+		writeTable(t, path, rows, cols, func(i, j int) float64 { return change(i, j, table.Get(i, j)) })
+	}
+	rewrite("d.txt", func(i, j int, v float64) float64 {
+		if (i+j)%5 == 0 {
+			return -9999
+		}
+		return v
+	})
+	rewrite("beta.txt", func(i, j int, v float64) float64 {
+		if i == 3 {
+			return 0
+		}
+		return v
+	})
+	rewrite("Vol.txt", func(i, j int, v float64) float64 {
+		if i == 3 {
+			return 1e3
+		}
+		return v
+	})
+
+	r := checkSolve(t, dir, models.DefaultClasses, truth)
+	want := 0
+	for i := range rows {
+		for j := range cols {
+			if (i+j)%5 != 0 && i != 3 {
+				want++
+			}
+		}
+	}
+	if got := r.NumValid(); got != want {
+		t.Errorf("валидных точек в матрице невязок: %d, ожидалось %d", got, want)
+	}
+}
+
+// readScene читает сцену из dir в параметры решателя
+func readScene(dir string, classes *models.ClassRegistry) models.InputParameters {
+	p := models.InputParameters{
+		Classes:        classes,
+		N:              make([]*models.Table, classes.Len()),
+		NPoints:        12,
+		NIters:         50,
+		NWorkers:       2,
+		NumPointsToAvg: 5,
+		Method:         MethodQR,
+	}
+	for _, c := range classes.Classes() {
+		p.N[c.Column] = reader.ReadTableOrPanic(filepath.Join(dir, c.File))
+	}
+	p.Beta = reader.ReadTableOrPanic(filepath.Join(dir, "beta.txt"))
+	p.Volume = reader.ReadTableOrPanic(filepath.Join(dir, "Vol.txt"))
+	return p
+}
+
+// checkSolve читает сцену из dir, решает задачу, сравнивает Cv с truth
+// и возвращает матрицу относительных невязок
+func checkSolve(t *testing.T, dir string, classes *models.ClassRegistry, truth map[string]float64) *models.Table {
+	t.Helper()
+	p := readScene(dir, classes)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	res, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	for _, c := range classes.Classes() {
+		if got := res.Cv[c.Column]; math.Abs(got-truth[c.Name]) > 1e-6*truth[c.Name] {
+			t.Errorf("Cv[%s] (столбец %d): получено %.6e, ожидалось %.6e", c.Name, c.Column, got, truth[c.Name])
+		}
+	}
+
+	r := RelativeDiscrepancy(p, res.Cv)
+	for k, v := range r.Data {
+		if math.Abs(v) > 1e-6 {
+			t.Fatalf("относительная невязка в точке %d: %g", k, v)
+		}
+	}
+	return r
+}
+
+// TestSolveSelection проверяет, что точки выбираются только из маски
+// p.Selection: значения V вне маски заведомо неверны
+func TestSolveSelection(t *testing.T) {
+	truth := map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}
+	rows, cols := 8, 7
+	dir := t.TempDir()
+	writeScene(t, dir, truth, rows, cols)
+
+	p := readScene(dir, models.DefaultClasses)
+	p.Selection = make([]bool, rows*cols)
+	for i := range rows {
+		for j := range cols {
+			// Маска непрямоугольная: слой, смещающийся по высоте
+			p.Selection[i*cols+j] = i >= j/2 && i <= j/2+3
+			if !p.Selection[i*cols+j] {
+				p.Volume.Set(i, j, 3*p.Volume.Get(i, j))
+			}
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	res, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range p.Classes.Classes() {
+		if got := res.Cv[c.Column]; math.Abs(got-truth[c.Name]) > 1e-6*truth[c.Name] {
+			t.Errorf("Cv[%s]: получено %.6e, ожидалось %.6e", c.Name, got, truth[c.Name])
+		}
+	}
+	if got, want := RelativeDiscrepancy(p, res.Cv).NumValid(), 4*cols; got != want {
+		t.Errorf("валидных точек в матрице невязок: %d, ожидалось %d", got, want)
+	}
+
+	p.Selection = p.Selection[1:]
+	if _, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p); err == nil {
+		t.Error("ожидалась ошибка для маски неверного размера")
+	}
+}
+
+// noisyScene читает сцену и искажает V шумом, чтобы решения выборок различались
+func noisyScene(t *testing.T) models.InputParameters {
+	t.Helper()
+	dir := t.TempDir()
+	writeScene(t, dir, map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}, 10, 9)
+	p := readScene(dir, models.DefaultClasses)
+	noise := rand.New(rand.NewSource(11))
+	for k := range p.Volume.Data {
+		p.Volume.Data[k] *= 1 + 0.05*noise.NormFloat64()
+	}
+	p.NPoints, p.NIters, p.NumPointsToAvg = 5, 300, 30
+	return p
+}
+
+// TestSolveWorkersDeterministic проверяет, что результат Solve не зависит
+// от числа потоков
+func TestSolveWorkersDeterministic(t *testing.T) {
+	p := noisyScene(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	solve := func(nWorkers int) models.OutputSolution {
+		p.NWorkers = nWorkers
+		res, err := NewSolver(logger, rand.New(rand.NewSource(7))).Solve(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	want := solve(1)
+	for _, nWorkers := range []int{2, 8} {
+		got := solve(nWorkers)
+		for k := range want.Cv {
+			if got.Cv[k] != want.Cv[k] {
+				t.Errorf("NWorkers=%d: Cv[%d] = %.17g, при одном потоке %.17g", nWorkers, k, got.Cv[k], want.Cv[k])
+			}
+		}
+		if got.Discrepancy != want.Discrepancy {
+			t.Errorf("NWorkers=%d: невязка %.17g, при одном потоке %.17g", nWorkers, got.Discrepancy, want.Discrepancy)
+		}
+	}
+}
+
+// TestSolveSeedReproducible проверяет, что два запуска с одним зерном
+// дают одинаковый результат, а с разными - разный
+func TestSolveSeedReproducible(t *testing.T) {
+	p := noisyScene(t)
+	p.NWorkers = 4
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	solve := func(seed int64) models.OutputSolution {
+		res, err := NewSolver(logger, rand.New(rand.NewSource(seed))).Solve(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	first, second := solve(42), solve(42)
+	for k := range first.Cv {
+		if first.Cv[k] != second.Cv[k] {
+			t.Errorf("Cv[%d]: %.17g и %.17g при одном зерне", k, first.Cv[k], second.Cv[k])
+		}
+	}
+	if first.Discrepancy != second.Discrepancy {
+		t.Errorf("невязка %.17g и %.17g при одном зерне", first.Discrepancy, second.Discrepancy)
+	}
+
+	other := solve(43)
+	if other.Cv[0] == first.Cv[0] && other.Discrepancy == first.Discrepancy {
+		t.Error("разные зерна дали одинаковый результат")
+	}
+}
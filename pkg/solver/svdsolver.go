@@ -9,102 +9,129 @@ import (
 	"gonum.org/v1/gonum/mat"
 )
 
-type CholProblem struct {
+// LinearProblem - сбалансированная система A x = b, решаемая выбранным методом
+type LinearProblem struct {
 	logger *slog.Logger
 	A      *mat.Dense
 	b      *mat.VecDense
-	labmda float64
+	solver LinearSolver
+	metric string    // метрика невязки, см. Metrics
+	sigma  []float64 // погрешности уравнений исходной системы (nil - не заданы)
+	scale  []float64 // множители, на которые умножены строки исходной системы
 }
 
-func NewCholProblem(matrix *mat.Dense, vector *mat.VecDense, lambda float64, logger *slog.Logger) *CholProblem {
-	A, b := BalanceProblem(matrix, vector)
-	return &CholProblem{
+// NewLinearProblem создает систему с единичными строками. Пустая метрика
+// означает rel-l2; chi2 недоступна, так как погрешности уравнений не заданы
+func NewLinearProblem(matrix *mat.Dense, vector *mat.VecDense, solver LinearSolver, metric string, logger *slog.Logger) (*LinearProblem, error) {
+	if metric == "" {
+		metric = MetricRelL2
+	}
+	if err := ValidateMetric(metric); err != nil {
+		return nil, err
+	}
+	if metric == MetricChi2 {
+		return nil, errChi2Sigma
+	}
+	scale := unitWeights(matrix)
+	A, b := scaleRows(matrix, vector, scale)
+	return &LinearProblem{
 		A:      A,
 		b:      b,
-		labmda: lambda,
+		solver: solver,
+		metric: metric,
 		logger: logger,
-	}
+		scale:  scale,
+	}, nil
 }
 
-func (p *CholProblem) Solve(xinit []float64) (models.OutputSolution, error) {
-
-	p.logger.Debug("", slog.Float64("Condition number", mat.Cond(p.A, 2)))
-	//fmt.Printf("Condition number: %f\n", mat.Cond(p.A, 2))
-
-	x, err := solveRegularizedLS(p.A, p.b, p.labmda)
+// NewWeightedLinearProblem создает систему, строки которой взвешены по
+// погрешностям уравнений sigma способом weighting (см. Weightings).
+// По sigma также считаются веса chi2 и погрешности коэффициентов
+func NewWeightedLinearProblem(matrix *mat.Dense, vector *mat.VecDense, sigma []float64, weighting string, solver LinearSolver, metric string, logger *slog.Logger) (*LinearProblem, error) {
+	if metric == "" {
+		metric = MetricRelL2
+	}
+	if err := ValidateMetric(metric); err != nil {
+		return nil, err
+	}
+	scale, err := rowWeights(matrix, vector, sigma, weighting)
+	if err != nil {
+		return nil, err
+	}
+	A, b := scaleRows(matrix, vector, scale)
+	return &LinearProblem{
+		A:      A,
+		b:      b,
+		solver: solver,
+		metric: metric,
+		logger: logger,
+		sigma:  sigma,
+		scale:  scale,
+	}, nil
+}
 
+func (p *LinearProblem) Solve() (models.OutputSolution, error) {
+	ls, err := p.solver.SolveLS(p.A, p.b)
 	if err != nil {
-		fmt.Println(err)
+		p.logger.Debug("Solver failed", slog.String("method", p.solver.Name()), slog.Any("error", err))
 		return models.OutputSolution{}, err
 	}
-
+	x := ls.X
 	result := x.RawVector().Data
 
-	p.logger.Debug("Solution", slog.Any("x", result),
+	p.logger.Debug("Solution",
+		slog.String("method", p.solver.Name()),
+		slog.Float64("Condition number", ls.Cond),
+		slog.Int("rank", ls.Rank),
+		slog.Any("x", result),
 		slog.Any("A", p.A),
-		slog.Any("b", p.b),
-		slog.Any("x", x))
-
-	// fmt.Println("Матрица A:")
-	// fmt.Printf(" %+.3f\n", mat.Formatted(p.A, mat.Prefix(" ")))
-
-	// fmt.Println("Вектор b:")
-	// fmt.Printf(" %+.3f\n", mat.Formatted(p.b, mat.Prefix(" ")))
-
-	// fmt.Println("Вектор x:")
-	// fmt.Printf(" %+.3f\n", mat.Formatted(x, mat.Prefix(" ")))
-	// Remove negative values from the solution vector
-	// for i := range result {
-	// 	if result[i] < 0 {
-	// 		result[i] = 0
-	// 	}
-	// }
-
-	F := mat.NewDense(p.A.RawMatrix().Rows, 1, nil)
-	F.Mul(p.A, x)
+		slog.Any("b", p.b))
+
+	// Невязка считается по всем уравнениям исходной системы (V/β):
+	// множители строк d_i снимаются
+	residual := mat.NewVecDense(p.b.Len(), nil)
+	residual.MulVec(p.A, x)
+	residual.SubVec(residual, p.b)
+	r, b := make([]float64, p.b.Len()), make([]float64, p.b.Len())
+	for i := range r {
+		r[i], b[i] = residual.AtVec(i)/p.scale[i], p.b.AtVec(i)/p.scale[i]
+	}
 
-	norm := 0.0
-	residual := mat.NewDense(p.A.RawMatrix().Rows, 1, nil)
-	residual.Sub(p.b, F)
+	var weights, cvErr []float64
+	if p.sigma != nil {
+		weights = make([]float64, len(p.sigma))
+		for i, s := range p.sigma {
+			if s > 0 {
+				weights[i] = 1 / (s * s)
+			}
+		}
+		if cvErr, err = coefficientErrors(p.A, p.scale, p.sigma, solverLambda(p.solver)); err != nil {
+			return models.OutputSolution{}, err
+		}
+	}
 
-	for i := range 3 {
-		norm += math.Pow(residual.At(i, 0)/p.b.AtVec(i), 2)
+	discrepancy, err := residualMetric(p.metric, r, b, weights)
+	if err != nil {
+		return models.OutputSolution{}, err
 	}
 
 	return models.OutputSolution{
-		Cv:          result,
-		Discrepancy: math.Sqrt(norm),
+		Cv:           result,
+		CvErr:        cvErr,
+		Discrepancy:  discrepancy,
+		Metric:       p.metric,
+		Method:       p.solver.Name(),
+		ResidualNorm: ls.ResidualNorm,
+		Cond:         ls.Cond,
+		Rank:         ls.Rank,
+		Active:       ls.Active,
 	}, nil
 }
 
 // BalanceProblem balances the problem by scaling rows to have unit L2 norm.
 // It returns a new matrix B and a new vector b
 func BalanceProblem(A *mat.Dense, b *mat.VecDense) (*mat.Dense, *mat.VecDense) {
-	m, n := A.Dims()
-	B := mat.NewDense(m, n, nil)
-	v := mat.NewVecDense(m, nil)
-
-	for i := range m {
-		// Получаем строку как срез
-		row := mat.Row(nil, i, A)
-		// Вычисляем L2-норму строки
-		norm := mat.Norm(mat.NewVecDense(n, row), 2)
-
-		if norm > 1e-12 { // Защита от нулевых строк
-			scale := 1.0 / norm
-			for j := range n {
-				B.Set(i, j, A.At(i, j)*scale)
-			}
-			v.SetVec(i, b.AtVec(i)*scale)
-		} else {
-			// Если строка нулевая — копируем как есть
-			for j := range n {
-				B.Set(i, j, A.At(i, j))
-			}
-			v.SetVec(i, b.AtVec(i))
-		}
-	}
-	return B, v
+	return scaleRows(A, b, unitWeights(A))
 }
 
 // Решает переопределённую СЛАУ с регуляризацией Тихонова
@@ -152,7 +179,8 @@ func solveRegularizedLS(A *mat.Dense, b *mat.VecDense, lambda float64) (*mat.Vec
 	return x, nil
 }
 
-func solveRegularizedLS1(A *mat.Dense, b *mat.VecDense, lambda float64) (*mat.VecDense, error) {
+// Решает нормальные уравнения с регуляризацией Тихонова через LU-разложение
+func solveRegularizedLU(A *mat.Dense, b *mat.VecDense, lambda float64) (*mat.VecDense, error) {
 	m, n := A.Dims()
 	if m <= n {
 		return nil, fmt.Errorf("ожидается переопределённая система (m > n), но m=%d, n=%d", m, n)
@@ -182,3 +210,62 @@ func solveRegularizedLS1(A *mat.Dense, b *mat.VecDense, lambda float64) (*mat.Ve
 
 	return x, nil
 }
+
+// Решает расширенную систему [A; sqrt(λ)I] x = [b; 0] через QR-разложение
+func solveRegularizedQR(A *mat.Dense, b *mat.VecDense, lambda float64) (*mat.VecDense, error) {
+	m, n := A.Dims()
+	if m <= n {
+		return nil, fmt.Errorf("ожидается переопределённая система (m > n), но m=%d, n=%d", m, n)
+	}
+
+	aug := mat.NewDense(m+n, n, nil)
+	aug.Slice(0, m, 0, n).(*mat.Dense).Copy(A)
+	augb := mat.NewVecDense(m+n, nil)
+	augb.SliceVec(0, m).(*mat.VecDense).CopyVec(b)
+	sq := math.Sqrt(lambda)
+	for i := range n {
+		aug.Set(m+i, i, sq)
+	}
+
+	var qr mat.QR
+	qr.Factorize(aug)
+	x := mat.NewVecDense(n, nil)
+	if err := qr.SolveVecTo(x, false, augb); err != nil {
+		return nil, fmt.Errorf("ошибка при решении: %v (λ=%g)", err, lambda)
+	}
+	return x, nil
+}
+
+// Решает систему через усеченное SVD: x = Σ f_i (u_iᵀb / σ_i) v_i,
+// где f_i = σ_i²/(σ_i²+λ), а σ_i < tol·σ_max отбрасываются.
+// Возвращает решение и число использованных сингулярных чисел
+func solveTruncatedSVD(A *mat.Dense, b *mat.VecDense, lambda, tol float64) (*mat.VecDense, int, error) {
+	m, n := A.Dims()
+	if m <= n {
+		return nil, 0, fmt.Errorf("ожидается переопределённая система (m > n), но m=%d, n=%d", m, n)
+	}
+
+	var svd mat.SVD
+	if !svd.Factorize(A, mat.SVDThin) {
+		return nil, 0, fmt.Errorf("SVD-разложение не сошлось")
+	}
+	var U, V mat.Dense
+	svd.UTo(&U)
+	svd.VTo(&V)
+	sigma := svd.Values(nil)
+
+	x := mat.NewVecDense(n, nil)
+	rank := 0
+	for i, s := range sigma {
+		if s <= tol*sigma[0] {
+			break
+		}
+		rank++
+		coef := mat.Dot(U.ColView(i), b) * s / (s*s + lambda)
+		x.AddScaledVec(x, coef, V.ColView(i))
+	}
+	if rank == 0 {
+		return nil, 0, fmt.Errorf("все сингулярные числа ниже порога")
+	}
+	return x, rank, nil
+}
@@ -0,0 +1,181 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Способы взвешивания строк системы, выбираемые флагом -weighting
+const (
+	WeightUnit  = "unit"  // строки приводятся к единичной норме (BalanceProblem)
+	WeightSigma = "sigma" // строки делятся на погрешность уравнения σ_i
+	WeightBoth  = "both"  // единичная норма, затем вес, обратный относительной погрешности σ_i/|b_i|
+)
+
+// Weightings перечисляет все способы взвешивания
+var Weightings = []string{WeightUnit, WeightSigma, WeightBoth}
+
+// ValidateWeighting проверяет имя способа взвешивания ("" - unit)
+func ValidateWeighting(weighting string) error {
+	if weighting == "" {
+		return nil
+	}
+	for _, w := range Weightings {
+		if w == weighting {
+			return nil
+		}
+	}
+	return fmt.Errorf("неизвестный способ взвешивания %q, допустимые: %s", weighting, strings.Join(Weightings, ", "))
+}
+
+// sigmaIterations - число пересчетов погрешностей уравнений по текущему
+// решению, если заданы погрешности долей (метод эффективной дисперсии)
+const sigmaIterations = 2
+
+// Uncertainty - погрешности системы A x = b
+type Uncertainty struct {
+	B []float64  // σ правой части b_i (nil - не заданы)
+	A *mat.Dense // σ элементов матрицы A_ik (nil - не заданы)
+}
+
+// Sigma возвращает погрешности уравнений σ_i² = σb_i² + Σ_k (x_k σA_ik)².
+// x нужен только при заданных σA и может быть nil
+func (u *Uncertainty) Sigma(x []float64) []float64 {
+	m := len(u.B)
+	if u.A != nil {
+		m, _ = u.A.Dims()
+	}
+	sigma := make([]float64, m)
+	for i := range sigma {
+		sum := 0.0
+		if u.B != nil {
+			sum = u.B[i] * u.B[i]
+		}
+		if u.A != nil && x != nil {
+			for k, xk := range x {
+				sum += math.Pow(xk*u.A.At(i, k), 2)
+			}
+		}
+		sigma[i] = math.Sqrt(sum)
+	}
+	return sigma
+}
+
+// unitWeights возвращает множители, приводящие строки A к единичной L2-норме.
+// Нулевые строки не масштабируются
+func unitWeights(A *mat.Dense) []float64 {
+	m, _ := A.Dims()
+	d := make([]float64, m)
+	for i := range d {
+		d[i] = 1
+		if norm := mat.Norm(A.RowView(i), 2); norm > 1e-12 {
+			d[i] = 1 / norm
+		}
+	}
+	return d
+}
+
+// rowWeights возвращает множители строк d_i для способа weighting.
+// Для both веса нормируются так, чтобы их среднеквадратичное было равно 1,
+// и параметр регуляризации сохранял масштаб системы с единичными строками
+func rowWeights(A *mat.Dense, b *mat.VecDense, sigma []float64, weighting string) ([]float64, error) {
+	if weighting == "" || weighting == WeightUnit {
+		return unitWeights(A), nil
+	}
+	if err := ValidateWeighting(weighting); err != nil {
+		return nil, err
+	}
+	for i, s := range sigma {
+		if !(s > 0) || math.IsInf(s, 1) {
+			return nil, fmt.Errorf("погрешность уравнения %d равна %g, взвешивание %s невозможно", i, s, weighting)
+		}
+	}
+
+	d := make([]float64, len(sigma))
+	if weighting == WeightSigma {
+		for i, s := range sigma {
+			d[i] = 1 / s
+		}
+		return d, nil
+	}
+
+	// both: d_i = w_i/||a_i||, где w_i ~ |b_i|/σ_i
+	rms := 0.0
+	for i, s := range sigma {
+		d[i] = math.Abs(b.AtVec(i)) / s
+		rms += d[i] * d[i]
+	}
+	rms = math.Sqrt(rms / float64(len(d)))
+	if rms == 0 {
+		return nil, fmt.Errorf("правая часть системы равна нулю, взвешивание %s невозможно", weighting)
+	}
+	for i, u := range unitWeights(A) {
+		d[i] *= u / rms
+	}
+	return d, nil
+}
+
+// scaleRows возвращает систему D A x = D b, D = diag(d)
+func scaleRows(A *mat.Dense, b *mat.VecDense, d []float64) (*mat.Dense, *mat.VecDense) {
+	m, n := A.Dims()
+	B := mat.NewDense(m, n, nil)
+	v := mat.NewVecDense(m, nil)
+	for i := range m {
+		for j := range n {
+			B.Set(i, j, A.At(i, j)*d[i])
+		}
+		v.SetVec(i, b.AtVec(i)*d[i])
+	}
+	return B, v
+}
+
+// coefficientErrors переносит погрешности уравнений на решение системы B x = c,
+// полученной из исходной умножением строк на d. Для оценки
+// x = (BᵀB + λI)⁻¹ Bᵀ c ковариация равна H⁻¹ Bᵀ S B H⁻¹, где H = BᵀB + λI,
+// S = diag((d_i σ_i)²). Ограничения NNLS и усечение SVD не учитываются
+func coefficientErrors(B *mat.Dense, d, sigma []float64, lambda float64) ([]float64, error) {
+	_, n := B.Dims()
+	var H mat.Dense
+	H.Mul(B.T(), B)
+	for k := range n {
+		H.Set(k, k, H.At(k, k)+lambda)
+	}
+	var Hinv mat.Dense
+	if err := Hinv.Inverse(&H); err != nil {
+		return nil, fmt.Errorf("погрешности коэффициентов: %v", err)
+	}
+	// M = H⁻¹ Bᵀ, дисперсия x_k = Σ_i M_ki² (d_i σ_i)²
+	var M mat.Dense
+	M.Mul(&Hinv, B.T())
+	errs := make([]float64, n)
+	for k := range errs {
+		sum := 0.0
+		for i, s := range sigma {
+			sum += math.Pow(M.At(k, i)*d[i]*s, 2)
+		}
+		errs[k] = math.Sqrt(sum)
+	}
+	return errs, nil
+}
+
+// solverLambda возвращает параметр регуляризации решателя (0 - без регуляризации)
+func solverLambda(ls LinearSolver) float64 {
+	switch s := ls.(type) {
+	case *CholeskySolver:
+		return s.Lambda
+	case *LUSolver:
+		return s.Lambda
+	case *QRSolver:
+		return s.Lambda
+	case *SVDSolver:
+		return s.Lambda
+	case *NNLSSolver:
+		return s.Lambda
+	case *NelderMeadSolver:
+		return s.Lambda
+	}
+	return 0
+}
@@ -0,0 +1,202 @@
+package solver
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"classification-project/internal/interface/reader"
+	"classification-project/internal/models"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TestCoefficientErrors сравнивает перенесенные погрешности коэффициентов с
+// разбросом решений по реализациям шума с заданными σ для всех способов взвешивания
+func TestCoefficientErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	m, n := 30, 3
+	A := mat.NewDense(m, n, nil)
+	sigma := make([]float64, m)
+	for i := range m {
+		for j := range n {
+			A.Set(i, j, rng.Float64())
+		}
+		sigma[i] = 0.01 * (1 + 9*rng.Float64())
+	}
+	x := mat.NewVecDense(n, []float64{3, 1.5, 0.8})
+	exact := mat.NewVecDense(m, nil)
+	exact.MulVec(A, x)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, weighting := range Weightings {
+		t.Run(weighting, func(t *testing.T) {
+			lp, err := NewWeightedLinearProblem(A, exact, sigma, weighting, &QRSolver{}, MetricChi2, logger)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sol, err := lp.Solve()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			const trials = 3000
+			sum, sum2 := make([]float64, n), make([]float64, n)
+			b := mat.NewVecDense(m, nil)
+			for range trials {
+				for i := range m {
+					b.SetVec(i, exact.AtVec(i)+sigma[i]*rng.NormFloat64())
+				}
+				lp, err := NewWeightedLinearProblem(A, b, sigma, weighting, &QRSolver{}, MetricChi2, logger)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s, err := lp.Solve()
+				if err != nil {
+					t.Fatal(err)
+				}
+				for k, v := range s.Cv {
+					sum[k] += v
+					sum2[k] += v * v
+				}
+			}
+			for k := range n {
+				mean := sum[k] / trials
+				sd := math.Sqrt(sum2[k]/trials - mean*mean)
+				if math.Abs(sol.CvErr[k]-sd) > 0.1*sd {
+					t.Errorf("Cv[%d]: перенесенная погрешность %.4g, разброс решений %.4g", k, sol.CvErr[k], sd)
+				}
+			}
+		})
+	}
+
+	// При взвешивании по σ и λ = 0 ковариация равна (Aᵀ Σ⁻¹ A)⁻¹
+	lp, err := NewWeightedLinearProblem(A, exact, sigma, WeightSigma, &CholeskySolver{}, MetricChi2, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sol, err := lp.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var F, cov mat.Dense
+	W := mat.NewDiagDense(m, nil)
+	for i, s := range sigma {
+		W.SetDiag(i, 1/(s*s))
+	}
+	F.Product(A.T(), W, A)
+	if err := cov.Inverse(&F); err != nil {
+		t.Fatal(err)
+	}
+	for k := range n {
+		if want := math.Sqrt(cov.At(k, k)); math.Abs(sol.CvErr[k]-want) > 1e-9*want {
+			t.Errorf("Cv[%d]: погрешность %.10g, ожидалось %.10g", k, sol.CvErr[k], want)
+		}
+	}
+	if sol.Discrepancy > 1e-12 {
+		t.Errorf("chi2 точного решения: %g", sol.Discrepancy)
+	}
+}
+
+// TestSolveSigmaWeighting проверяет, что взвешивание по σ подавляет точки с
+// заведомо искаженным V, если их погрешность велика, а без таблиц погрешностей
+// sigma-взвешивание отклоняется
+func TestSolveSigmaWeighting(t *testing.T) {
+	truth := map[string]float64{"d": 3.0e6, "u": 1.5e6, "s": 0.8e6}
+	rows, cols := 8, 7
+	dir := t.TempDir()
+	writeScene(t, dir, truth, rows, cols)
+
+	p := models.InputParameters{
+		Classes:        models.DefaultClasses,
+		N:              make([]*models.Table, 3),
+		NPoints:        12,
+		NIters:         50,
+		NWorkers:       2,
+		NumPointsToAvg: 5,
+		Method:         MethodQR,
+	}
+	for _, c := range p.Classes.Classes() {
+		p.N[c.Column] = reader.ReadTableOrPanic(filepath.Join(dir, c.File))
+	}
+	p.Beta = reader.ReadTableOrPanic(filepath.Join(dir, "beta.txt"))
+	p.Volume = reader.ReadTableOrPanic(filepath.Join(dir, "Vol.txt"))
+	p.SigmaVolume = models.NewTable(rows, cols, nil, p.Volume.ColumnLabels, p.Volume.RowLabels)
+	for i := range rows {
+		for j := range cols {
+			v := p.Volume.Get(i, j)
+			p.SigmaVolume.Set(i, j, 1e-6*v)
+			if (i+j)%3 == 0 {
+				p.Volume.Set(i, j, 1.5*v)
+				p.SigmaVolume.Set(i, j, v)
+			}
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	relErr := func(weighting string) (float64, models.OutputSolution) {
+		p.Weighting = weighting
+		res, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p)
+		if err != nil {
+			t.Fatalf("%s: %v", weighting, err)
+		}
+		worst := 0.0
+		for _, c := range p.Classes.Classes() {
+			worst = math.Max(worst, math.Abs(res.Cv[c.Column]/truth[c.Name]-1))
+		}
+		return worst, res
+	}
+
+	unitErr, unit := relErr(WeightUnit)
+	sigmaErr, res := relErr(WeightSigma)
+	if sigmaErr > 1e-3 || sigmaErr > unitErr/10 {
+		t.Errorf("относительная ошибка Cv: sigma %.2e, unit %.2e", sigmaErr, unitErr)
+	}
+	if len(unit.CvErr) != 3 || len(res.CvErr) != 3 {
+		t.Fatalf("ожидались погрешности Cv, получено %v и %v", unit.CvErr, res.CvErr)
+	}
+	for k, e := range res.CvErr {
+		if !(e > 0) || e > 1e-2*res.Cv[k] {
+			t.Errorf("погрешность Cv[%d] = %g при Cv = %g", k, e, res.Cv[k])
+		}
+	}
+
+	// Погрешности долей добавляются к σ уравнений через текущее решение
+	p.SigmaN = make([]*models.Table, 3)
+	for k := range p.SigmaN {
+		p.SigmaN[k] = models.NewTable(rows, cols, nil, p.Volume.ColumnLabels, p.Volume.RowLabels)
+		for i := range p.SigmaN[k].Data {
+			p.SigmaN[k].Data[i] = 1e-9
+		}
+	}
+	if bothErr, res := relErr(WeightBoth); bothErr > 1e-3 || len(res.CvErr) != 3 {
+		t.Errorf("both: относительная ошибка Cv %.2e, погрешности %v", bothErr, res.CvErr)
+	}
+
+	p.SigmaN, p.SigmaVolume = nil, nil
+	p.Weighting = WeightSigma
+	if _, err := NewSolver(logger, rand.New(rand.NewSource(1))).Solve(p); err == nil {
+		t.Error("ожидалась ошибка взвешивания без таблиц погрешностей")
+	}
+}
+
+// TestUncertaintySigma проверяет сложение погрешностей правой части и долей
+func TestUncertaintySigma(t *testing.T) {
+	u := &Uncertainty{
+		B: []float64{3, 0},
+		A: mat.NewDense(2, 2, []float64{1, 0, 0.5, 0.5}),
+	}
+	got := u.Sigma([]float64{4, 2})
+	want := []float64{5, math.Sqrt(5)}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Errorf("σ[%d] = %g, ожидалось %g", i, got[i], want[i])
+		}
+	}
+	if got := u.Sigma(nil); got[0] != 3 || got[1] != 0 {
+		t.Errorf("σ без решения: %v", got)
+	}
+}